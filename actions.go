@@ -0,0 +1,178 @@
+package notifications
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Standard media control actions, with freedesktop icon names so daemons
+// advertising action-icons can show them as icon buttons
+
+// PreviousAction returns a "Previous" button calling handler
+func PreviousAction(handler func(track *TrackInfo)) Action {
+	return Action{ID: "previous", Label: "Previous", Icon: "media-skip-backward", Handler: handler}
+}
+
+// PlayPauseAction returns a "Pause" button calling handler, which reads
+// "Play" on notifications for a paused or stopped track
+func PlayPauseAction(handler func(track *TrackInfo)) Action {
+	return Action{ID: "play-pause", Label: "Pause", Icon: "media-playback-pause", Handler: handler}
+}
+
+// playPause returns action labelled for the playback state, if it is a
+// PlayPauseAction
+func playPause(action Action, state PlaybackState) Action {
+	if action.ID != "play-pause" {
+		return action
+	}
+	if state == StatePlaying {
+		action.Label, action.Icon = "Pause", "media-playback-pause"
+	} else {
+		action.Label, action.Icon = "Play", "media-playback-start"
+	}
+	return action
+}
+
+// NextAction returns a "Next" button calling handler
+func NextAction(handler func(track *TrackInfo)) Action {
+	return Action{ID: "next", Label: "Next", Icon: "media-skip-forward", Handler: handler}
+}
+
+// SnoozeAction returns a "Mute for 30 min" button (for d = 30 minutes)
+// calling handler
+func SnoozeAction(d time.Duration, handler func(track *TrackInfo)) Action {
+	return Action{ID: "snooze", Label: "Mute for " + snoozeLabel(d), Icon: "notifications-disabled", Handler: handler}
+}
+
+// snoozeLabel formats a snooze period for a button, e.g. "30 min" or
+// "1 h 30 min"
+func snoozeLabel(d time.Duration) string {
+	hours, minutes := int(d/time.Hour), int(d%time.Hour/time.Minute)
+	switch {
+	case hours == 0:
+		return fmt.Sprintf("%d min", max(minutes, 1))
+	case minutes == 0:
+		return fmt.Sprintf("%d h", hours)
+	}
+	return fmt.Sprintf("%d h %d min", hours, minutes)
+}
+
+// BlockArtistAction returns a "Don't notify for this artist" button
+// calling handler
+func BlockArtistAction(handler func(track *TrackInfo)) Action {
+	return Action{ID: "block-artist", Label: "Don't notify for this artist", Icon: "list-remove", Handler: handler}
+}
+
+// CopyTrackAction returns a "Copy" button calling handler
+func CopyTrackAction(handler func(track *TrackInfo)) Action {
+	return Action{ID: "copy-track", Label: "Copy", Icon: "edit-copy", Handler: handler}
+}
+
+// ratingStars is the number of rating buttons
+const ratingStars = 5
+
+// RatingActions returns five buttons, "★" to "★★★★★", calling handler with
+// the number of stars
+func RatingActions(handler func(track *TrackInfo, stars int)) []Action {
+	actions := make([]Action, ratingStars)
+	for i := range actions {
+		stars := i + 1
+		actions[i] = Action{
+			ID:      "rate-" + strconv.Itoa(stars),
+			Label:   strings.Repeat("★", stars),
+			Handler: func(track *TrackInfo) { handler(track, stars) },
+		}
+	}
+	return actions
+}
+
+// RateAction returns a single "Rate…" button calling handler with 0 stars,
+// for the app to ask for the rating itself
+func RateAction(handler func(track *TrackInfo, stars int)) Action {
+	return Action{
+		ID:      "rate",
+		Label:   "Rate…",
+		Icon:    "starred",
+		Handler: func(track *TrackInfo) { handler(track, 0) },
+	}
+}
+
+// RaiseAction returns the "default" action, invoked by clicking the
+// notification's body, calling handler to bring the player to the front
+func RaiseAction(handler func(track *TrackInfo)) Action {
+	return Action{
+		ID:      "default",
+		Label:   "Show Player",
+		Handler: handler,
+	}
+}
+
+// actions returns the actions for a track notification: the rendered ones,
+// Options.Actions, the Options.SnoozeFor, Options.BlockArtistAction and
+// Options.CopyTrackAction buttons, the rating buttons with Options.OnRate (a single "Rate…" button
+// on daemons that can't show all five stars) and a click on the body
+// raising the player. A PlayPauseAction is labelled for the state.
+func (n *Notifier) actions(rendered []Action, caps Capabilities, state PlaybackState) []Action {
+	actions := slices.Clip(rendered)
+	for _, action := range n.opts().Actions {
+		actions = append(actions, playPause(action, state))
+	}
+	if d := n.opts().SnoozeFor; d > 0 {
+		actions = append(actions, SnoozeAction(d, func(*TrackInfo) { n.Snooze(d) }))
+	}
+	if n.opts().BlockArtistAction {
+		actions = append(actions, BlockArtistAction(func(track *TrackInfo) { n.BlockArtist(track.Artist) }))
+	}
+	if n.opts().CopyTrackAction {
+		actions = append(actions, CopyTrackAction(func(track *TrackInfo) {
+			if err := n.CopyTrack(track); err != nil {
+				n.NotifyError("Couldn't copy the track", err)
+			}
+		}))
+	}
+	if n.opts().OnRate != nil {
+		if caps.MaxActions > 0 && len(actions)+ratingStars > caps.MaxActions {
+			actions = append(actions, RateAction(n.opts().OnRate))
+		} else {
+			actions = append(actions, RatingActions(n.opts().OnRate)...)
+		}
+	}
+	if raise := n.raise(); raise != nil && !hasAction(actions, "default") {
+		actions = append(actions, RaiseAction(raise))
+	}
+	return actions
+}
+
+// raise returns what brings the player to the front: Options.OnRaise, or
+// else the Watcher's MPRIS Raise (nil for neither)
+func (n *Notifier) raise() func(track *TrackInfo) {
+	if n.opts().OnRaise != nil {
+		return n.opts().OnRaise
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.raiser
+}
+
+// setRaiser sets the fallback for Options.OnRaise
+func (n *Notifier) setRaiser(raiser func(track *TrackInfo)) {
+	if n == nil {
+		return
+	}
+	n.mu.Lock()
+	n.raiser = raiser
+	n.mu.Unlock()
+}
+
+// hasAction reports whether actions include one with the ID
+func hasAction(actions []Action, id string) bool {
+	for _, action := range actions {
+		if action.ID == id {
+			return true
+		}
+	}
+	return false
+}
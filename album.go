@@ -0,0 +1,79 @@
+package notifications
+
+import (
+	"strconv"
+	"strings"
+)
+
+// albumListMax is how many tracks a squashed album notification lists
+const albumListMax = 5
+
+// albumSquash is the running tracklist of the album being played
+type albumSquash struct {
+	key    string       // Album the tracklist belongs to
+	tracks []albumEntry // Most recent last
+}
+
+// albumEntry is one track of a squashed album
+type albumEntry struct {
+	key   string // Track key
+	label string // e.g. "3. Song A"
+}
+
+// squashAlbum records track in the running tracklist and, from the second
+// track of an album on, renders the tracklist in place of the body, so an
+// album plays as one updating notification
+func (n *Notifier) squashAlbum(renderer Renderer, track *TrackInfo) Renderer {
+	if track.Album == "" {
+		n.album = albumSquash{}
+		return renderer
+	}
+
+	key := collapseSpace(track.Artist) + "-" + collapseSpace(track.Album)
+	if key != n.album.key {
+		n.album = albumSquash{key: key}
+	}
+
+	// Resuming or restarting the current track doesn't add it again
+	current := trackKey(track)
+	if tracks := n.album.tracks; len(tracks) == 0 || tracks[len(tracks)-1].key != current {
+		n.album.tracks = append(n.album.tracks, albumEntry{key: current, label: albumLabel(track)})
+		if len(n.album.tracks) > albumListMax {
+			n.album.tracks = n.album.tracks[1:]
+		}
+	}
+	if len(n.album.tracks) < 2 {
+		return renderer
+	}
+
+	lines := make([]string, len(n.album.tracks))
+	for i, entry := range n.album.tracks {
+		lines[i] = entry.label + " ✓"
+	}
+	lines[len(lines)-1] = n.album.tracks[len(lines)-1].label + " ▶"
+
+	summary := track.Album
+	if track.Artist != "" {
+		summary = track.Artist + " — " + track.Album
+	}
+	body := strings.Join(lines, "\n")
+
+	return RendererFunc(func(track *TrackInfo, state PlaybackState) (Payload, error) {
+		payload, err := renderer.Render(track, state)
+		if err != nil {
+			return payload, err
+		}
+		payload.Summary = summary
+		payload.Body = body
+		payload.Markup = false
+		return payload, nil
+	})
+}
+
+// albumLabel formats a track for the tracklist, e.g. "3. Song A"
+func albumLabel(track *TrackInfo) string {
+	if track.TrackNumber > 0 {
+		return strconv.Itoa(track.TrackNumber) + ". " + track.Title
+	}
+	return track.Title
+}
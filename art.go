@@ -0,0 +1,369 @@
+package notifications
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	// Register decoders for the formats album art usually comes in
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+// localArtPath maps an ImageURL to a local file path, if it refers to one
+func localArtPath(imageURL string) (string, bool) {
+	if strings.HasPrefix(imageURL, "file://") {
+		u, err := url.Parse(imageURL)
+		if err != nil {
+			return "", false
+		}
+		return u.Path, true
+	}
+	if filepath.IsAbs(imageURL) {
+		return imageURL, true
+	}
+	return "", false
+}
+
+// sampleArtPNG is the album art of SendTest's sample track
+//
+//go:embed sample_art.png
+var sampleArtPNG []byte
+
+// sampleArt returns the path of the sample track's art, writing it to the
+// cache first if needed, so SendTest covers loading art from a file
+func sampleArt() (string, error) {
+	dir, err := cacheDir("art")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "sample.png")
+	if info, err := os.Stat(path); err == nil && info.Size() == int64(len(sampleArtPNG)) {
+		return path, nil
+	}
+	return path, writeCached(dir, path, bytes.NewReader(sampleArtPNG))
+}
+
+// localHost reports whether host is on the local network: a private,
+// loopback or link-local address, or a name without a public domain
+func localHost(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast()
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if !strings.Contains(host, ".") {
+		return true // "localhost", "nas"
+	}
+	for _, suffix := range []string{".local", ".lan", ".home.arpa", ".internal"} {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultArtTimeout bounds how long downloading art may delay a notification
+const defaultArtTimeout = 3 * time.Second
+
+const (
+	// maxArtSize caps downloaded art, which is never legitimately this large
+	maxArtSize = 16 << 20
+
+	// maxArtPixels caps decoded art, so a small file claiming huge
+	// dimensions can't make decoding allocate gigabytes
+	maxArtPixels = 4096 * 4096
+
+	// maxArtCache caps the downloaded art kept on disk; the least recently
+	// used files are removed first
+	maxArtCache = 128 << 20
+)
+
+// artLoader resolves ImageURLs to decoded images, downloading remote art
+// into an on-disk cache so repeated plays don't download it again
+type artLoader struct {
+	client  *http.Client
+	fetcher func(url string) ([]byte, error) // Replaces client (nil for none)
+	timeout time.Duration
+
+	// skipDownloads reports whether only cached art may be used, for
+	// Options.PowerSaving (nil for never)
+	skipDownloads func() bool
+
+	// Connectivity is only watched once art actually needs downloading,
+	// so players with local art never connect to the system bus
+	networkOnce sync.Once
+	network     *connectivity
+
+	// The last decoded image, since progress and live updates re-send the
+	// same art every few seconds. Players that overwrite a fixed cover
+	// file per track are caught by the modification time and size.
+	mu       sync.Mutex
+	lastURL  string
+	lastPath string
+	lastInfo os.FileInfo
+	lastImg  *image.NRGBA
+}
+
+// newArtLoader creates an art loader with the configured download timeout
+func newArtLoader(options Options) *artLoader {
+	timeout := options.ArtTimeout
+	if timeout <= 0 {
+		timeout = defaultArtTimeout
+	}
+	return &artLoader{
+		client:  &http.Client{Timeout: timeout},
+		fetcher: options.ArtFetcher,
+		timeout: timeout,
+	}
+}
+
+// close stops watching connectivity
+func (l *artLoader) close() error {
+	l.networkOnce.Do(func() {}) // Don't start watching while closing
+	return l.network.close()
+}
+
+// online reports whether remote art can be downloaded, starting to watch
+// connectivity if that hadn't been needed yet
+func (l *artLoader) online() bool {
+	l.networkOnce.Do(func() { l.network = watchConnectivity() })
+	return l.network.online()
+}
+
+// reachable is online for art at imageURL, which may be on the local
+// network
+func (l *artLoader) reachable(imageURL string) bool {
+	l.networkOnce.Do(func() { l.network = watchConnectivity() })
+	u, err := url.Parse(imageURL)
+	if err != nil {
+		return l.network.online()
+	}
+	return l.network.reachable(u.Hostname())
+}
+
+// load resolves an ImageURL to a local file and decodes it
+func (l *artLoader) load(imageURL string) (string, *image.NRGBA, error) {
+	path, ok := localArtPath(imageURL)
+	if !ok {
+		var err error
+		if path, err = l.fetch(imageURL); err != nil {
+			return "", nil, err
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if imageURL == l.lastURL && l.lastInfo != nil &&
+		info.ModTime().Equal(l.lastInfo.ModTime()) && info.Size() == l.lastInfo.Size() {
+		return l.lastPath, l.lastImg, nil
+	}
+
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	if config.Width*config.Height > maxArtPixels {
+		return "", nil, fmt.Errorf("failed to decode %s: %dx%d is too large", path, config.Width, config.Height)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", nil, err
+	}
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+
+	nrgba := toNRGBA(img)
+	l.lastURL, l.lastPath, l.lastInfo, l.lastImg = imageURL, path, info, nrgba
+	return path, nrgba, nil
+}
+
+// fetch downloads remote art into the cache, keyed by a hash of the URL,
+// and returns the cached file
+func (l *artLoader) fetch(imageURL string) (string, error) {
+	if l.fetcher == nil && !strings.HasPrefix(imageURL, "http://") && !strings.HasPrefix(imageURL, "https://") {
+		return "", fmt.Errorf("unsupported image URL %q", imageURL)
+	}
+
+	dir, err := cacheDir("art")
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, cacheKey(imageURL))
+	if fileExists(path) {
+		touch(path)
+		return path, nil
+	}
+	if l.skipDownloads != nil && l.skipDownloads() {
+		return "", fmt.Errorf("failed to download art: saving power")
+	}
+
+	if l.fetcher != nil {
+		data, err := l.fetchWith(imageURL)
+		if err != nil {
+			return "", fmt.Errorf("failed to fetch art: %w", err)
+		}
+		return path, writeCached(dir, path, bytes.NewReader(data))
+	}
+
+	if !l.reachable(imageURL) {
+		return "", fmt.Errorf("failed to download art: offline")
+	}
+
+	resp, err := l.client.Get(imageURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to download art: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download art: %s", resp.Status)
+	}
+
+	if err := writeCached(dir, path, resp.Body); err != nil {
+		return "", fmt.Errorf("failed to download art: %w", err)
+	}
+	return path, nil
+}
+
+// fetchWith calls the host app's fetcher, giving up after the art timeout.
+// A fetcher that doesn't return in time is left to finish on its own.
+func (l *artLoader) fetchWith(imageURL string) ([]byte, error) {
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, err := l.fetcher(imageURL)
+		done <- result{data, err}
+	}()
+
+	timer := time.NewTimer(l.timeout)
+	defer timer.Stop()
+	select {
+	case r := <-done:
+		if r.err == nil && len(r.data) == 0 {
+			r.err = fmt.Errorf("no data")
+		}
+		return r.data, r.err
+	case <-timer.C:
+		return nil, fmt.Errorf("%w after %v", ErrTimeout, l.timeout)
+	}
+}
+
+// writeCached stores art at path, evicting old art beyond maxArtCache. It
+// is written to a temporary file first, so a partial download is never
+// cached, and art larger than maxArtSize is rejected rather than truncated.
+func writeCached(dir, path string, r io.Reader) error {
+	tmp, err := os.CreateTemp(dir, "download-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	n, err := io.Copy(tmp, io.LimitReader(r, maxArtSize+1))
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n > maxArtSize {
+		err = fmt.Errorf("art is larger than %d bytes", maxArtSize)
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	pruneCache(dir, maxArtCache)
+	return nil
+}
+
+// toNRGBA converts an image to non-premultiplied 8-bit RGBA, the pixel
+// layout the notification spec expects
+func toNRGBA(img image.Image) *image.NRGBA {
+	if nrgba, ok := img.(*image.NRGBA); ok && nrgba.Rect.Min == (image.Point{}) {
+		return nrgba
+	}
+	bounds := img.Bounds()
+	nrgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+	return nrgba
+}
+
+// fitImage downscales img so its pixels take at most maxBytes, keeping the
+// aspect ratio. It returns nil if even a single pixel doesn't fit.
+func fitImage(img *image.NRGBA, maxBytes int) *image.NRGBA {
+	if len(img.Pix) <= maxBytes {
+		return img
+	}
+	if maxBytes < 4 {
+		return nil
+	}
+
+	bounds := img.Bounds()
+	scale := math.Sqrt(float64(maxBytes) / float64(bounds.Dx()*bounds.Dy()*4))
+	width := max(1, int(float64(bounds.Dx())*scale))
+	height := max(1, int(float64(bounds.Dy())*scale))
+	return downscale(img, width, height)
+}
+
+// downscale resizes img to width x height by averaging the source pixels
+// covering each destination pixel
+func downscale(img *image.NRGBA, width, height int) *image.NRGBA {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0, y1 := y*srcH/height, max((y+1)*srcH/height, y*srcH/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*srcW/width, max((x+1)*srcW/width, x*srcW/width+1)
+
+			var r, g, b, a, count int
+			for sy := y0; sy < y1; sy++ {
+				row := img.Pix[sy*img.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					b += int(p[2])
+					a += int(p[3])
+					count++
+				}
+			}
+
+			d := dst.Pix[y*dst.Stride+x*4:]
+			d[0] = uint8(r / count)
+			d[1] = uint8(g / count)
+			d[2] = uint8(b / count)
+			d[3] = uint8(a / count)
+		}
+	}
+	return dst
+}
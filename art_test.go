@@ -0,0 +1,97 @@
+package notifications
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArtLoaderReusesDecodedImage(t *testing.T) {
+	dir := t.TempDir()
+	cover := filepath.Join(dir, "cover.png")
+	writePNG(t, cover, 4, 4)
+	loader := newArtLoader(DefaultOptions(""))
+	defer loader.close()
+
+	_, first, err := loader.load(cover)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modified := time.Now().Add(time.Minute)
+	for _, tt := range []struct {
+		name     string
+		change   func(t *testing.T)
+		imageURL string
+		wantSame bool
+		wantSize int
+	}{
+		{name: "unchanged", imageURL: cover, wantSame: true, wantSize: 4},
+		{name: "file URL", imageURL: "file://" + cover, wantSize: 4},
+		{
+			name: "touched",
+			change: func(t *testing.T) {
+				if err := os.Chtimes(cover, modified, modified); err != nil {
+					t.Fatal(err)
+				}
+			},
+			imageURL: cover,
+			wantSize: 4,
+		},
+		{
+			name: "overwritten",
+			change: func(t *testing.T) {
+				// Same time as before, so only the size differs
+				writePNG(t, cover, 8, 8)
+				if err := os.Chtimes(cover, modified, modified); err != nil {
+					t.Fatal(err)
+				}
+			},
+			imageURL: cover,
+			wantSize: 8,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.change != nil {
+				tt.change(t)
+			}
+			path, img, err := loader.load(tt.imageURL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if path != cover {
+				t.Errorf("path = %q, want %q", path, cover)
+			}
+			if same := img == first; same != tt.wantSame {
+				t.Errorf("reused the decoded image: %v, want %v", same, tt.wantSame)
+			}
+			if size := img.Bounds().Dx(); size != tt.wantSize {
+				t.Errorf("width = %d, want %d", size, tt.wantSize)
+			}
+			first = img
+		})
+	}
+}
+
+func TestArtLoaderErrors(t *testing.T) {
+	dir := t.TempDir()
+	notImage := filepath.Join(dir, "cover.png")
+	if err := os.WriteFile(notImage, []byte("not a PNG"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	loader := newArtLoader(DefaultOptions(""))
+	defer loader.close()
+
+	for _, tt := range []struct {
+		imageURL string
+		wantErr  string
+	}{
+		{filepath.Join(dir, "missing.png"), "no such file"},
+		{notImage, "failed to decode"},
+	} {
+		if _, _, err := loader.load(tt.imageURL); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("load(%q) error = %v, want one containing %q", tt.imageURL, err, tt.wantErr)
+		}
+	}
+}
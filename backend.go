@@ -0,0 +1,173 @@
+package notifications
+
+import (
+	"fmt"
+	"image"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Notification is a fully rendered notification, ready for a backend to
+// deliver
+type Notification struct {
+	AppName      string
+	DesktopEntry string // Sending app's .desktop file name (empty for none)
+	Icon         string // Icon name or path, already resolved
+	Summary      string
+	Body         string
+	Urgency      Urgency
+	Actions      []Action
+	ReplacesID   uint32 // Notification to replace (0 = new notification)
+	Timeout      int32  // Milliseconds (-1 = default, 0 = never)
+	Transient    bool   // Keep out of the notification history
+	Resident     bool   // Keep open when an action is invoked
+	Progress     int    // Percentage for a progress gauge (-1 for none)
+
+	SoundName     string // Themed sound to play (empty for the default)
+	SoundFile     string // Sound file to play (empty for the default)
+	SuppressSound bool   // Play no sound at all
+
+	ImagePath string       // Local copy of the album art (empty for none)
+	Image     *image.NRGBA // Decoded album art (nil for none)
+
+	Event Event         // What the notification is about
+	Track *TrackInfo    // Track it is about (nil for messages)
+	State PlaybackState // Playback state it shows (empty for messages)
+	Time  time.Time     // When it was sent; earlier for notifications replayed after being offline
+}
+
+// Backend delivers notifications to a notification service
+type Backend interface {
+	// Send shows a notification and returns its ID, which is passed back
+	// as ReplacesID to update it. Backends that can't replace
+	// notifications may return 0.
+	Send(note *Notification) (uint32, error)
+
+	// Close releases the backend's resources
+	Close() error
+
+	// Capabilities returns the features the service supports
+	Capabilities() Capabilities
+}
+
+// Dismisser is implemented by backends that can close notifications
+type Dismisser interface {
+	Dismiss(id uint32) error
+}
+
+// PresentationDetector is implemented by backends that can tell whether
+// the desktop is presenting or sharing the screen
+type PresentationDetector interface {
+	PresentationActive() bool
+}
+
+// DoNotDisturbDetector is implemented by backends that can tell whether
+// the user turned on Do Not Disturb
+type DoNotDisturbDetector interface {
+	DoNotDisturb() bool
+}
+
+// CapabilityLister is implemented by backends that can list the raw
+// capability strings of the service
+type CapabilityLister interface {
+	ListCapabilities() ([]string, error)
+}
+
+// ServerInfo identifies the notification service
+type ServerInfo struct {
+	Name        string `json:"name"` // e.g. "gnome-shell", "Plasma", "dunst"
+	Vendor      string `json:"vendor"`
+	Version     string `json:"version"`
+	SpecVersion string `json:"spec_version"` // Version of the notification spec it implements
+}
+
+// ServerInfoProvider is implemented by backends that can identify the
+// notification service
+type ServerInfoProvider interface {
+	ServerInfo() (ServerInfo, error)
+}
+
+// RemoteBackend is implemented by backends delivering over the network,
+// for the notifier to pace them with Options.RemoteRateLimit
+type RemoteBackend interface {
+	// Destination names the service notifications go to, e.g. the push
+	// server's host. Notifiers sending to the same destination share its
+	// rate limit.
+	Destination() string
+}
+
+// EventSubscriber is implemented by backends that only want some events.
+// Backends that don't implement it receive EventStarted and EventMessage.
+type EventSubscriber interface {
+	Subscribes(event Event) bool
+}
+
+// BackendConfig is passed to a BackendFactory
+type BackendConfig struct {
+	Options Options
+
+	// OnAction must be called when the user invokes action key (an
+	// Action.ID) on notification id
+	OnAction func(id uint32, key string)
+
+	// OnClosed must be called when notification id is closed
+	OnClosed func(id uint32, reason CloseReason)
+}
+
+// BackendFactory opens a backend
+type BackendFactory func(config BackendConfig) (Backend, error)
+
+var (
+	backendsMu sync.RWMutex
+	backends   = make(map[string]BackendFactory)
+)
+
+// Register makes a backend available by name, for Options.Backend. It is
+// meant to be called from init functions and panics if factory is nil or
+// the name is already registered.
+func Register(name string, factory BackendFactory) {
+	backendsMu.Lock()
+	defer backendsMu.Unlock()
+
+	if factory == nil {
+		panic("notifications: Register factory is nil")
+	}
+	if _, dup := backends[name]; dup {
+		panic("notifications: Register called twice for backend " + name)
+	}
+	backends[name] = factory
+}
+
+// Backends returns the names of the registered backends, sorted
+func Backends() []string {
+	backendsMu.RLock()
+	defer backendsMu.RUnlock()
+
+	names := make([]string, 0, len(backends))
+	for name := range backends {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// openBackend opens the backend registered under name
+func openBackend(name string, config BackendConfig) (Backend, error) {
+	backendsMu.RLock()
+	factory, ok := backends[name]
+	backendsMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown notification backend %q", name)
+	}
+	return factory(config)
+}
+
+// firstID seeds the IDs of backends that number notifications themselves.
+// Starting from the clock rather than 0 keeps new notifications from
+// reusing the IDs of a previous run, whose replace ID may be restored from
+// a Store.
+func firstID() uint32 {
+	return uint32(time.Now().UnixMilli())
+}
@@ -0,0 +1,65 @@
+package notifications
+
+import (
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writePNG writes a blank width x height PNG to path
+func writePNG(tb testing.TB, path string, width, height int) {
+	tb.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, image.NewNRGBA(image.Rect(0, 0, width, height))); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// benchmarkTrack is a typical track for the benchmarks
+var benchmarkTrack = TrackInfo{Title: "Song", Artist: "Simon & Garfunkel", Album: "Bookends", Source: "mpd"}
+
+// BenchmarkRender renders a track with the default renderer, as for a
+// daemon with markup
+func BenchmarkRender(b *testing.B) {
+	renderer := DefaultOptions("").renderer(Capabilities{Images: true, Markup: true})
+	track := benchmarkTrack
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := renderer.Render(&track, StatePlaying); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRenderArtCacheHit renders a track and loads its 1000x800
+// cover, which the art loader has already decoded
+func BenchmarkRenderArtCacheHit(b *testing.B) {
+	renderer := DefaultOptions("").renderer(Capabilities{Images: true, Markup: true})
+	track := benchmarkTrack
+	track.ImageURL = filepath.Join(b.TempDir(), "cover.png")
+	writePNG(b, track.ImageURL, 1000, 800)
+	loader := newArtLoader(DefaultOptions(""))
+	defer loader.close()
+	if _, _, err := loader.load(track.ImageURL); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		payload, err := renderer.Render(&track, StatePlaying)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, _, err := loader.load(payload.ImageURL); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// blockedArtistsKey is the Store key for the artists muted with BlockArtist
+const blockedArtistsKey = "blocked-artists"
+
+// BlockArtist stops track notifications for an artist, matched
+// case-insensitively, and shows a transient confirmation. With
+// Options.Store the artist stays muted across restarts.
+func (n *Notifier) BlockArtist(artist string) error {
+	if n == nil || artist == "" {
+		return nil
+	}
+	n.callMu.Lock()
+	defer n.callMu.Unlock()
+
+	key := strings.ToLower(artist)
+	if _, ok := n.blockedArtists[key]; !ok {
+		n.blockedArtists[key] = artist
+	}
+	if err := n.saveBlockedArtists(); err != nil {
+		return err
+	}
+	note := n.message(Payload{
+		Summary: "Muted " + artist,
+		Body:    "Their tracks won't be announced anymore",
+	})
+	note.transient = true // Only confirms the click
+	return n.send(note, 0)
+}
+
+// UnblockArtist resumes track notifications for an artist muted with
+// BlockArtist
+func (n *Notifier) UnblockArtist(artist string) error {
+	if n == nil {
+		return nil
+	}
+	n.callMu.Lock()
+	defer n.callMu.Unlock()
+
+	delete(n.blockedArtists, strings.ToLower(artist))
+	return n.saveBlockedArtists()
+}
+
+// BlockedArtists returns the artists muted with BlockArtist, sorted
+func (n *Notifier) BlockedArtists() []string {
+	if n == nil {
+		return nil
+	}
+	n.callMu.Lock()
+	defer n.callMu.Unlock()
+	return n.blockedList()
+}
+
+// blockedList returns the muted artists as they were first spelled, sorted
+func (n *Notifier) blockedList() []string {
+	artists := make([]string, 0, len(n.blockedArtists))
+	for _, artist := range n.blockedArtists {
+		artists = append(artists, artist)
+	}
+	slices.Sort(artists)
+	return artists
+}
+
+// blocked reports whether the track's artist was muted with BlockArtist
+func (n *Notifier) blocked(track *TrackInfo) bool {
+	if track.Artist == "" {
+		return false
+	}
+	_, ok := n.blockedArtists[strings.ToLower(track.Artist)]
+	return ok
+}
+
+// loadBlockedArtists restores the artists muted in earlier runs
+func (n *Notifier) loadBlockedArtists() {
+	if n.opts().Store == nil {
+		return
+	}
+	data, err := n.opts().Store.Get(blockedArtistsKey)
+	if err != nil || data == nil {
+		return
+	}
+	var artists []string
+	if err := json.Unmarshal(data, &artists); err != nil {
+		return
+	}
+	for _, artist := range artists {
+		n.blockedArtists[strings.ToLower(artist)] = artist
+	}
+}
+
+// saveBlockedArtists persists the muted artists
+func (n *Notifier) saveBlockedArtists() error {
+	if n.opts().Store == nil {
+		return nil
+	}
+	data, err := json.Marshal(n.blockedList())
+	if err != nil {
+		return err
+	}
+	if err := n.opts().Store.Put(blockedArtistsKey, data); err != nil {
+		return fmt.Errorf("failed to save blocked artists: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package notifications_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/go-music-players/notifications"
+)
+
+func TestBlockArtist(t *testing.T) {
+	store := notifications.NewMemoryStore()
+	notifier, fake := newNotifier(t, notifications.WithStore(store))
+
+	if err := notifier.BlockArtist("Nickelback"); err != nil {
+		t.Fatal(err)
+	}
+	fake.AssertLast(t, "Muted Nickelback", "Their tracks won't be announced anymore")
+	if call, _ := fake.Last(); call.Hints["transient"] != true {
+		t.Errorf("confirmation hints = %v, want transient", call.Hints)
+	}
+
+	fake.Reset()
+	for _, artist := range []string{"Nickelback", "NICKELBACK", "nickelback"} {
+		if err := notifier.Notify(&notifications.TrackInfo{Title: "Song by " + artist, Artist: artist}, notifications.StatePlaying); err != nil {
+			t.Fatal(err)
+		}
+		if reason := notifier.LastDelivery().Reason; reason != notifications.SuppressBlockedArtist {
+			t.Errorf("%s: suppression reason = %q, want %q", artist, reason, notifications.SuppressBlockedArtist)
+		}
+	}
+	fake.AssertNone(t)
+
+	// Muted artists are restored by a notifier sharing the store
+	restarted, _ := newNotifier(t, notifications.WithStore(store))
+	if blocked := restarted.BlockedArtists(); !slices.Equal(blocked, []string{"Nickelback"}) {
+		t.Errorf("blocked artists after restart = %q, want [Nickelback]", blocked)
+	}
+
+	if err := restarted.UnblockArtist("NICKELBACK"); err != nil {
+		t.Fatal(err)
+	}
+	if blocked := restarted.BlockedArtists(); len(blocked) != 0 {
+		t.Errorf("blocked artists after unblocking = %q, want none", blocked)
+	}
+	again, fake := newNotifier(t, notifications.WithStore(store))
+	if err := again.Notify(&notifications.TrackInfo{Title: "Song", Artist: "Nickelback"}, notifications.StatePlaying); err != nil {
+		t.Fatal(err)
+	}
+	fake.AssertCount(t, 1)
+}
+
+func TestBlockArtistAction(t *testing.T) {
+	notifier, fake := newNotifier(t, notifications.WithOptions(func(o *notifications.Options) {
+		o.BlockArtistAction = true
+	}))
+	if err := notifier.Notify(&notifications.TrackInfo{Title: "Song", Artist: "Band"}, notifications.StatePlaying); err != nil {
+		t.Fatal(err)
+	}
+	call, _ := fake.Last()
+	if !slices.Contains(call.Actions, "block-artist") {
+		t.Fatalf("actions = %q, want block-artist", call.Actions)
+	}
+
+	fake.Invoke(call.ID, "block-artist")
+	if blocked := notifier.BlockedArtists(); !slices.Equal(blocked, []string{"Band"}) {
+		t.Errorf("blocked artists = %q, want [Band]", blocked)
+	}
+}
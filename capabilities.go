@@ -0,0 +1,134 @@
+package notifications
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// Capabilities describes which notification features a backend supports.
+// Renderers consult it so unsupported features degrade gracefully instead of
+// being sent and silently ignored.
+type Capabilities struct {
+	Images      bool // Album art and other images can be shown
+	Actions     bool // Action buttons are supported
+	ActionIcons bool // Action buttons can be shown as icons
+	Replacement bool // Notifications can be replaced in place
+	Markup      bool // The body may contain markup
+	Progress    bool // A progress gauge can be shown
+	MaxActions  int  // Most action buttons shown at once (0 if unknown)
+}
+
+// degrade removes the parts of a payload the backend can't display
+func (p Payload) degrade(caps Capabilities) Payload {
+	if !caps.Images {
+		p.ImageURL = ""
+	}
+	if !caps.Actions {
+		p.Actions = nil
+	}
+	return p
+}
+
+// bodyFor returns the body in the form the backend expects: markup is
+// stripped for backends without markup support, and plain text is escaped
+// for backends with it, so "Mumford & Sons" isn't taken for an entity
+func (p Payload) bodyFor(caps Capabilities) string {
+	switch {
+	case p.Markup && !caps.Markup:
+		return stripMarkup(p.Body)
+	case !p.Markup && caps.Markup:
+		return EscapeMarkup(p.Body)
+	}
+	return p.Body
+}
+
+// markupTag matches the tags of body markup
+var markupTag = regexp.MustCompile(`<[^>]*>`)
+
+// stripMarkup turns body markup into plain text, so a backend without
+// markup support doesn't show the tags literally
+func stripMarkup(body string) string {
+	return html.UnescapeString(markupTag.ReplaceAllString(body, ""))
+}
+
+// markupEscaper escapes the characters that are special in body markup
+var markupEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// EscapeMarkup escapes &, < and > so text can be placed in body markup,
+// e.g. track metadata in a custom renderer
+func EscapeMarkup(text string) string {
+	return markupEscaper.Replace(text)
+}
+
+// Features is what the whole pipeline can do in the current environment:
+// the backend in use, the daemon behind it and the notifier's own
+// features, for settings screens to offer only what works. Fields are only
+// added, and are named the same in JSON.
+type Features struct {
+	Backend  string     `json:"backend"`  // Backend in use, e.g. "dbus"
+	Fallback bool       `json:"fallback"` // Backend is a fallback, as the preferred one couldn't be opened
+	Remote   bool       `json:"remote"`   // Notifications are delivered off this machine, by a RemoteBackend such as "unifiedpush"
+	Server   ServerInfo `json:"server"`   // Notification service (zero if the backend can't tell)
+
+	Art         bool `json:"art"`          // Album art is shown
+	RemoteArt   bool `json:"remote_art"`   // Remote art URLs are downloaded (false while offline or saving power)
+	Actions     bool `json:"actions"`      // Action buttons are shown
+	MaxActions  int  `json:"max_actions"`  // Most action buttons shown at once (0 if unknown)
+	Markup      bool `json:"markup"`       // Bodies may contain markup
+	Progress    bool `json:"progress"`     // Progress gauges are shown
+	Replacement bool `json:"replacement"`  // Notifications are updated in place
+	LiveUpdates bool `json:"live_updates"` // StartLiveUpdates and StartLyrics refresh (false while saving power)
+	Dismiss     bool `json:"dismiss"`      // Clear and DismissAll close the popups
+
+	DoNotDisturb bool `json:"do_not_disturb"` // Do Not Disturb mode is detected
+	Presentation bool `json:"presentation"`   // Presentation mode and screen sharing are detected
+	Watching     bool `json:"watching"`       // A Watcher feeds this notifier from MPRIS players
+	Coordinated  bool `json:"coordinated"`    // Coordinating with other instances, see Options.Coordinate
+	Primary      bool `json:"primary"`        // This instance shows track notifications
+
+	PowerProfile string `json:"power_profile"` // Active power profile, e.g. "power-saver" (empty if unknown or not watched)
+}
+
+// Features reports what the pipeline can do. Unlike Capabilities, which is
+// what the backend supports, it covers everything between Notify and the
+// screen.
+func (n *Notifier) Features() Features {
+	if n == nil {
+		return Features{}
+	}
+	caps := n.backend.Capabilities()
+	saving := n.powerSaving()
+	preferred := n.opts().Backend
+	if preferred == "" {
+		preferred = defaultBackend
+	}
+
+	f := Features{
+		Backend:  n.backendName,
+		Fallback: n.backendName != preferred,
+
+		Art:         caps.Images,
+		RemoteArt:   caps.Images && !saving.SkipArtDownloads && (n.opts().ArtFetcher != nil || n.art.online()),
+		Actions:     caps.Actions,
+		MaxActions:  caps.MaxActions,
+		Markup:      caps.Markup,
+		Progress:    caps.Progress,
+		Replacement: caps.Replacement,
+		LiveUpdates: caps.Replacement && !saving.NoLiveUpdates,
+
+		Coordinated: n.coord != nil,
+		Primary:     n.coord.active(),
+
+		PowerProfile: n.activeProfile(),
+	}
+	n.mu.Lock()
+	f.Watching = n.raiser != nil
+	n.mu.Unlock()
+	f.Server, _ = n.ServerInfo()
+	_, f.Dismiss = n.backend.(Dismisser)
+	_, f.Remote = n.backend.(RemoteBackend)
+	_, f.DoNotDisturb = n.backend.(DoNotDisturbDetector)
+	_, f.Presentation = n.backend.(PresentationDetector)
+	return f
+}
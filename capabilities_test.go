@@ -0,0 +1,50 @@
+package notifications
+
+import "testing"
+
+func TestBodyFor(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		body   string
+		markup bool
+		caps   Capabilities
+		want   string
+	}{
+		{"markup kept", "<b>Mumford &amp; Sons</b>", true, Capabilities{Markup: true}, "<b>Mumford &amp; Sons</b>"},
+		{"markup stripped", "<b>Mumford &amp; Sons</b>", true, Capabilities{}, "Mumford & Sons"},
+		{"text escaped", "Mumford & Sons <live>", false, Capabilities{Markup: true}, "Mumford &amp; Sons &lt;live&gt;"},
+		{"text kept", "Mumford & Sons", false, Capabilities{}, "Mumford & Sons"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := Payload{Body: tt.body, Markup: tt.markup}
+			if got := payload.bodyFor(tt.caps); got != tt.want {
+				t.Errorf("bodyFor = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDegrade(t *testing.T) {
+	payload := Payload{ImageURL: "/tmp/cover.png", Actions: []Action{{ID: "next"}}}
+	for _, tt := range []struct {
+		name        string
+		caps        Capabilities
+		wantImage   bool
+		wantActions bool
+	}{
+		{"everything", Capabilities{Images: true, Actions: true}, true, true},
+		{"no images", Capabilities{Actions: true}, false, true},
+		{"no actions", Capabilities{Images: true}, true, false},
+		{"nothing", Capabilities{}, false, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got := payload.degrade(tt.caps)
+			if (got.ImageURL != "") != tt.wantImage {
+				t.Errorf("image = %q, want one: %v", got.ImageURL, tt.wantImage)
+			}
+			if (got.Actions != nil) != tt.wantActions {
+				t.Errorf("actions = %v, want some: %v", got.Actions, tt.wantActions)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package notifications
+
+import (
+	"strconv"
+	"time"
+)
+
+// Chapter is a section of a podcast episode or audiobook
+type Chapter struct {
+	Start    time.Duration // Offset into the track
+	Title    string
+	ImageURL string // Chapter art (empty to use the track's)
+}
+
+// chapter returns the index of the chapter playing at the track's
+// position, or -1 if it has none
+func (t *TrackInfo) chapter() int {
+	current := -1
+	for i, chapter := range t.Chapters {
+		if chapter.Start > t.Position {
+			break
+		}
+		current = i
+	}
+	return current
+}
+
+// currentChapter returns the chapter playing at the track's position, or
+// nil
+func (t *TrackInfo) currentChapter() *Chapter {
+	if i := t.chapter(); i >= 0 {
+		return &t.Chapters[i]
+	}
+	return nil
+}
+
+// chapterKey identifies a chapter of a track for deduplication, or is
+// empty if none is playing
+func chapterKey(key string, track *TrackInfo) string {
+	i := track.chapter()
+	if i < 0 {
+		return ""
+	}
+	return key + "#" + strconv.Itoa(i)
+}
+
+// chapterChanged reports whether a new chapter of the same track started,
+// remembering it
+func (n *Notifier) chapterChanged(key string, track *TrackInfo) bool {
+	chapter := chapterKey(key, track)
+	if chapter == "" || chapter == n.chapterKey {
+		return false
+	}
+	n.chapterKey = chapter
+	return n.opts().NotifyOnChapter
+}
+
+// chapterRenderer renders chapter notifications: the chapter title as
+// summary, the episode and show as body, and the chapter's art
+type chapterRenderer struct {
+	Capabilities Capabilities
+}
+
+// Render implements Renderer
+func (r chapterRenderer) Render(track *TrackInfo, state PlaybackState) (Payload, error) {
+	chapter := track.currentChapter()
+	if chapter == nil {
+		return DefaultRenderer{Capabilities: r.Capabilities}.Render(track, state)
+	}
+
+	body := track.Title
+	if show := track.Album; show != "" {
+		body += "\n" + show
+	}
+	if state == StatePaused {
+		body = "⏸ " + body
+	}
+
+	payload := Payload{
+		Summary: chapter.Title,
+		Body:    body,
+	}
+	if payload.Summary == "" {
+		payload.Summary = "Chapter " + strconv.Itoa(track.chapter()+1)
+	}
+	if r.Capabilities.Images {
+		payload.ImageURL = chapter.ImageURL
+		if payload.ImageURL == "" {
+			payload.ImageURL = track.ImageURL
+		}
+	}
+	return payload, nil
+}
+
+// chapterRenderer returns the renderer used for chapter changes
+func (o Options) chapterRenderer(caps Capabilities) Renderer {
+	if o.ChapterRenderer != nil {
+		return o.ChapterRenderer
+	}
+	return chapterRenderer{Capabilities: caps}
+}
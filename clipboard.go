@@ -0,0 +1,88 @@
+package notifications
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// defaultCopyTemplate is what CopyTrack copies without Options.CopyTemplate
+const defaultCopyTemplate = "{{if .Artist}}{{.Artist}} – {{end}}{{.Title}}"
+
+// copyTemplate returns the template CopyTrack formats tracks with
+func (o *Options) copyTemplate() string {
+	if o.CopyTemplate != "" {
+		return o.CopyTemplate
+	}
+	return defaultCopyTemplate
+}
+
+// CopyTrack copies a track's "Artist – Title", or Options.CopyTemplate
+// executed with a TemplateData, to the clipboard
+func (n *Notifier) CopyTrack(track *TrackInfo) error {
+	if n == nil || track == nil {
+		return nil
+	}
+	tmpl, err := parseTemplate("copy", n.opts().copyTemplate())
+	if err != nil {
+		return err
+	}
+	text, err := execute(tmpl, TemplateData{TrackInfo: track, Chapter: track.currentChapter()})
+	if err != nil {
+		return err
+	}
+	return copyToClipboard(text)
+}
+
+// clipboardCommands are the tools that set the clipboard from their
+// input, in order of preference, on systems without a native one. wl-copy
+// is only tried in Wayland sessions.
+var clipboardCommands = [][]string{
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+}
+
+// copyToClipboard puts text on the clipboard through the platform's tool:
+// pbcopy on macOS, PowerShell's Set-Clipboard on Windows, and wl-copy,
+// xclip or xsel elsewhere
+func copyToClipboard(text string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("pbcopy")
+	case "windows":
+		cmd = exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-Command",
+			"[Console]::InputEncoding = [Text.Encoding]::UTF8; Set-Clipboard -Value ([Console]::In.ReadToEnd())")
+	default:
+		for _, command := range clipboardCommands {
+			if command[0] == "wl-copy" && os.Getenv("WAYLAND_DISPLAY") == "" {
+				continue
+			}
+			if _, err := exec.LookPath(command[0]); err == nil {
+				cmd = exec.Command(command[0], command[1:]...)
+				break
+			}
+		}
+		if cmd == nil {
+			return fmt.Errorf("%w: no clipboard tool found; install wl-clipboard, xclip or xsel", ErrCapabilityMissing)
+		}
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && stderr.Len() > 0 {
+		return fmt.Errorf("failed to copy to the clipboard: %s: %s", cmd.Args[0], strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy to the clipboard: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package notifications_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/go-music-players/notifications"
+	"github.com/go-music-players/notifications/notificationstest"
+)
+
+// fakeClipboard puts a wl-copy first on $PATH that runs script, with
+// $CLIPBOARD naming the file standing in for the clipboard
+func fakeClipboard(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("the clipboard tool is fixed on", runtime.GOOS)
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "wl-copy"), []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	clipboard := filepath.Join(dir, "clipboard")
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv("WAYLAND_DISPLAY", "wayland-test")
+	t.Setenv("CLIPBOARD", clipboard)
+	return clipboard
+}
+
+func TestCopyTrack(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		template string
+		track    notifications.TrackInfo
+		want     string
+	}{
+		{"default", "", notifications.TrackInfo{Title: "Song", Artist: "Band", Album: "Album"}, "Band – Song"},
+		{"title only", "", notifications.TrackInfo{Title: "Station ID"}, "Station ID"},
+		{"template", "{{.Artist}} {{.Title}} {{.Album}}", notifications.TrackInfo{Title: "Song", Artist: "Band", Album: "Album"}, "Band Song Album"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			clipboard := fakeClipboard(t, `cat > "$CLIPBOARD"`)
+			notifier, _ := newNotifier(t, notifications.WithOptions(func(o *notifications.Options) {
+				o.CopyTemplate = tt.template
+			}))
+			if err := notifier.CopyTrack(&tt.track); err != nil {
+				t.Fatal(err)
+			}
+			if got, err := os.ReadFile(clipboard); err != nil || string(got) != tt.want {
+				t.Errorf("clipboard = %q (%v), want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyTrackAction(t *testing.T) {
+	clipboard := fakeClipboard(t, `cat > "$CLIPBOARD"`)
+	notifier, fake := newNotifier(t, notifications.WithOptions(func(o *notifications.Options) {
+		o.CopyTrackAction = true
+	}))
+	if err := notifier.Notify(&notifications.TrackInfo{Title: "Song", Artist: "Band"}, notifications.StatePlaying); err != nil {
+		t.Fatal(err)
+	}
+	call, _ := fake.Last()
+	if !slices.Contains(call.Actions, "copy-track") {
+		t.Fatalf("actions = %q, want copy-track", call.Actions)
+	}
+
+	fake.Invoke(call.ID, "copy-track")
+	if got, err := os.ReadFile(clipboard); err != nil || string(got) != "Band – Song" {
+		t.Errorf("clipboard = %q (%v), want %q", got, err, "Band – Song")
+	}
+
+	// A failed copy is reported as an error notification
+	t.Setenv("CLIPBOARD", filepath.Join(t.TempDir(), "missing", "clipboard"))
+	fake.Invoke(call.ID, "copy-track")
+	fake.AssertShown(t, "Couldn't copy the track")
+}
+
+func TestCopyTrackWithoutClipboard(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("the clipboard tool is fixed on", runtime.GOOS)
+	}
+	t.Setenv("PATH", t.TempDir())
+	notifier, _ := newNotifier(t)
+	err := notifier.CopyTrack(&notifications.TrackInfo{Title: "Song"})
+	if !errors.Is(err, notifications.ErrCapabilityMissing) {
+		t.Errorf("error = %v, want ErrCapabilityMissing", err)
+	}
+}
+
+func TestInvalidCopyTemplate(t *testing.T) {
+	notifier, err := notificationstest.NewNotifier(notificationstest.NewFake(), notifications.WithOptions(func(o *notifications.Options) {
+		o.CopyTemplate = "{{.Artist"
+	}))
+	if err == nil {
+		notifier.Close()
+		t.Error("an invalid copy template was accepted")
+	}
+}
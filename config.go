@@ -0,0 +1,368 @@
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// configPollInterval is how often a ConfigWatcher checks the file
+const configPollInterval = 2 * time.Second
+
+// configVersion is the version of the config file format. Files without a
+// version predate versioning and are version 0.
+const configVersion = 1
+
+// configMigrations upgrade the settings of a config file from version i
+// to i+1
+var configMigrations = []func(settings map[string]any){
+	// Version 1 only added the version key
+	func(map[string]any) {},
+}
+
+// Config is the part of Options that can be kept in a JSON config file,
+// for users to change without rebuilding the player. Settings missing
+// from the file are left as they are.
+type Config struct {
+	Version int `json:"version,omitempty"` // Format version; older files are migrated when loaded
+
+	AppName         *string `json:"app_name,omitempty"`
+	Icon            *string `json:"icon,omitempty"`
+	Timeout         *int32  `json:"timeout_ms,omitempty"`
+	NotifyOnPause   *bool   `json:"notify_on_pause,omitempty"`
+	ClearOnStop     *bool   `json:"clear_on_stop,omitempty"`
+	ReplaceExisting *bool   `json:"replace_existing,omitempty"`
+	DesktopEntry    *string `json:"desktop_entry,omitempty"`
+	Urgency         *string `json:"urgency,omitempty"` // "low", "normal" or "critical"
+	Transient       *bool   `json:"transient,omitempty"`
+	SoundName       *string `json:"sound_name,omitempty"`
+	SoundFile       *string `json:"sound_file,omitempty"`
+	SuppressSound   *bool   `json:"suppress_sound,omitempty"`
+
+	SummaryTemplate *string                `json:"summary_template,omitempty"`
+	BodyTemplate    *string                `json:"body_template,omitempty"`
+	Templates       map[string]TemplateSet `json:"templates,omitempty"`
+	Locale          *string                `json:"locale,omitempty"`
+
+	NotifyOnChapter *bool        `json:"notify_on_chapter,omitempty"`
+	ChapterTemplate *TemplateSet `json:"chapter_template,omitempty"`
+
+	ShowLoudness *bool `json:"show_loudness,omitempty"`
+	ShowProgress *bool `json:"show_progress,omitempty"`
+	ShowOrigin   *bool `json:"show_origin,omitempty"`
+	SquashAlbums *bool `json:"squash_albums,omitempty"`
+
+	SuppressDuringPresentation *bool `json:"suppress_during_presentation,omitempty"`
+	RespectDoNotDisturb        *bool `json:"respect_do_not_disturb,omitempty"`
+	DoNotDisturbAllowErrors    *bool `json:"do_not_disturb_allow_errors,omitempty"`
+
+	Rules []Rule `json:"rules,omitempty"`
+
+	Backend          *string  `json:"backend,omitempty"`
+	FallbackBackends []string `json:"fallback_backends,omitempty"`
+}
+
+// ConfigPath returns where an app's notification settings are kept:
+// notifications.json in its directory under $XDG_CONFIG_HOME (usually
+// ~/.config/<app>)
+func ConfigPath(app string) (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to find config directory: %w", err)
+	}
+	return filepath.Join(dir, app, "notifications.json"), nil
+}
+
+// LoadConfig reads a config file. Unknown settings are errors, so typos
+// don't go unnoticed.
+func LoadConfig(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config: %w", err)
+	}
+	return parseConfig(data)
+}
+
+// parseConfig decodes and validates a config file's contents, migrating
+// them from older versions
+func parseConfig(data []byte) (Config, error) {
+	data, _, err := migrateConfig(data)
+	if err != nil {
+		return Config{}, err
+	}
+
+	var config Config
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+	if config.Urgency != nil {
+		if _, ok := parseUrgency(*config.Urgency); !ok {
+			return Config{}, fmt.Errorf("invalid config: unknown urgency %q", *config.Urgency)
+		}
+	}
+	return config, nil
+}
+
+// migrateConfig upgrades a config file's contents to configVersion,
+// reporting whether they needed it
+func migrateConfig(data []byte) ([]byte, bool, error) {
+	var settings map[string]any
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, false, fmt.Errorf("invalid config: %w", err)
+	}
+
+	version := 0
+	if v, ok := settings["version"]; ok {
+		f, ok := v.(float64)
+		if !ok || f < 0 || f != float64(int(f)) {
+			return nil, false, fmt.Errorf("invalid config: bad version %v", v)
+		}
+		version = int(f)
+	}
+	switch {
+	case version > configVersion:
+		return nil, false, fmt.Errorf("config version %d is newer than the supported version %d", version, configVersion)
+	case version == configVersion:
+		return data, false, nil
+	}
+
+	if settings == nil {
+		settings = make(map[string]any) // The file was null
+	}
+	for _, migrate := range configMigrations[version:] {
+		migrate(settings)
+	}
+	settings["version"] = configVersion
+	data, err := json.MarshalIndent(settings, "", "    ")
+	return data, true, err
+}
+
+// MigrateConfig upgrades the config file at path to the current format in
+// place, keeping the original as path + ".bak". It reports whether the
+// file was migrated; current files are left untouched.
+func MigrateConfig(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config: %w", err)
+	}
+	original, err := os.ReadFile(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config: %w", err)
+	}
+	migrated, ok, err := migrateConfig(original)
+	if err != nil || !ok {
+		return false, err
+	}
+	if _, err := parseConfig(migrated); err != nil {
+		return false, err
+	}
+
+	if err := os.WriteFile(path+".bak", original, info.Mode().Perm()); err != nil {
+		return false, fmt.Errorf("failed to back up config: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to write config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(migrated, '\n')); err != nil {
+		tmp.Close()
+		return false, fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return false, fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return false, fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return false, fmt.Errorf("failed to write config: %w", err)
+	}
+	return true, nil
+}
+
+// urgencyNames are the names of the urgencies in config files
+var urgencyNames = map[Urgency]string{
+	UrgencyLow:      "low",
+	UrgencyNormal:   "normal",
+	UrgencyCritical: "critical",
+}
+
+// parseUrgency parses an urgency name
+func parseUrgency(name string) (Urgency, bool) {
+	for urgency, n := range urgencyNames {
+		if n == name {
+			return urgency, true
+		}
+	}
+	return UrgencyNormal, false
+}
+
+// WithConfig applies the settings in a config file
+func WithConfig(config Config) Option {
+	return optionFunc(config.apply)
+}
+
+// apply copies the settings present in the config
+func (c Config) apply(o *Options) {
+	set(&o.AppName, c.AppName)
+	set(&o.Icon, c.Icon)
+	set(&o.Timeout, c.Timeout)
+	set(&o.NotifyOnPause, c.NotifyOnPause)
+	set(&o.ClearOnStop, c.ClearOnStop)
+	set(&o.ReplaceExisting, c.ReplaceExisting)
+	set(&o.DesktopEntry, c.DesktopEntry)
+	if c.Urgency != nil {
+		o.Urgency, _ = parseUrgency(*c.Urgency)
+	}
+	set(&o.Transient, c.Transient)
+	set(&o.SoundName, c.SoundName)
+	set(&o.SoundFile, c.SoundFile)
+	set(&o.SuppressSound, c.SuppressSound)
+
+	set(&o.SummaryTemplate, c.SummaryTemplate)
+	set(&o.BodyTemplate, c.BodyTemplate)
+	if c.Templates != nil {
+		o.Templates = c.Templates
+	}
+	set(&o.Locale, c.Locale)
+
+	set(&o.NotifyOnChapter, c.NotifyOnChapter)
+	set(&o.ChapterTemplate, c.ChapterTemplate)
+
+	set(&o.ShowLoudness, c.ShowLoudness)
+	set(&o.ShowProgress, c.ShowProgress)
+	set(&o.ShowOrigin, c.ShowOrigin)
+	set(&o.SquashAlbums, c.SquashAlbums)
+
+	set(&o.SuppressDuringPresentation, c.SuppressDuringPresentation)
+	set(&o.RespectDoNotDisturb, c.RespectDoNotDisturb)
+	set(&o.DoNotDisturbAllowErrors, c.DoNotDisturbAllowErrors)
+
+	if c.Rules != nil {
+		o.Rules = c.Rules
+	}
+
+	set(&o.Backend, c.Backend)
+	if c.FallbackBackends != nil {
+		o.FallbackBackends = c.FallbackBackends
+	}
+}
+
+// set copies a config value that is present
+func set[T any](setting *T, value *T) {
+	if value != nil {
+		*setting = *value
+	}
+}
+
+// ConfigWatcher applies a config file to a notifier, and applies it again
+// whenever the file changes
+type ConfigWatcher struct {
+	notifier *Notifier
+	base     Options // Notifier's settings the file applies on top of
+	path     string
+	stop     chan struct{}
+	done     chan struct{}
+
+	mu      sync.Mutex
+	modTime time.Time // Of the file last applied
+	size    int64
+	err     error // From the last reload
+}
+
+// NewConfigWatcher applies the config file at path to notifier and starts
+// watching it. The file's settings apply on top of the notifier's settings
+// as of now, so removing a setting from the file (or the whole file)
+// reverts it. A missing file is not an error; its settings apply once it
+// is created.
+func NewConfigWatcher(notifier *Notifier, path string) (*ConfigWatcher, error) {
+	w := &ConfigWatcher{
+		notifier: notifier,
+		base:     notifier.baseOptions(),
+		path:     path,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	if err := w.reload(); err != nil {
+		return nil, err
+	}
+	go w.run()
+	return w, nil
+}
+
+// Close stops watching, keeping the settings last applied
+func (w *ConfigWatcher) Close() error {
+	close(w.stop)
+	<-w.done
+	return nil
+}
+
+// Err returns why the file couldn't be applied the last time it changed,
+// or nil. The previous settings stay in effect until it is fixed.
+func (w *ConfigWatcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// run polls the file until Close is called
+func (w *ConfigWatcher) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		}
+		err := w.reload()
+		w.mu.Lock()
+		w.err = err
+		w.mu.Unlock()
+	}
+}
+
+// reload applies the file if it changed since it was last applied
+func (w *ConfigWatcher) reload() error {
+	var modTime time.Time
+	var size int64
+	info, err := os.Stat(w.path)
+	switch {
+	case err == nil:
+		modTime, size = info.ModTime(), info.Size()
+	case !os.IsNotExist(err):
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	w.mu.Lock()
+	unchanged := modTime.Equal(w.modTime) && size == w.size
+	w.mu.Unlock()
+	if unchanged {
+		return nil
+	}
+
+	config := Config{}
+	if !modTime.IsZero() {
+		if config, err = LoadConfig(w.path); err != nil {
+			return err
+		}
+	}
+	if err := w.notifier.Reconfigure(w.base, WithConfig(config)); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	w.modTime, w.size = modTime, size
+	w.mu.Unlock()
+	return nil
+}
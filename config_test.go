@@ -0,0 +1,168 @@
+package notifications
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		data    string
+		wantErr string
+		check   func(t *testing.T, o Options)
+	}{
+		{
+			name: "settings",
+			data: `{"version": 1, "app_name": "Player", "timeout_ms": 3000, "urgency": "low", "fallback_backends": ["notify-send"]}`,
+			check: func(t *testing.T, o Options) {
+				if o.AppName != "Player" || o.Timeout != 3000 || o.Urgency != UrgencyLow {
+					t.Errorf("app name, timeout, urgency = %q, %d, %d; want Player, 3000, low", o.AppName, o.Timeout, o.Urgency)
+				}
+				if !slices.Equal(o.FallbackBackends, []string{"notify-send"}) {
+					t.Errorf("fallback backends = %q, want [notify-send]", o.FallbackBackends)
+				}
+			},
+		},
+		{
+			name: "missing settings kept",
+			data: `{"version": 1, "notify_on_pause": true}`,
+			check: func(t *testing.T, o Options) {
+				if !o.NotifyOnPause || o.AppName != "Defaults" {
+					t.Errorf("notify on pause, app name = %v, %q; want true, Defaults", o.NotifyOnPause, o.AppName)
+				}
+			},
+		},
+		{
+			name: "unversioned",
+			data: `{"app_name": "Old"}`,
+			check: func(t *testing.T, o Options) {
+				if o.AppName != "Old" {
+					t.Errorf("app name = %q, want Old", o.AppName)
+				}
+			},
+		},
+		{
+			name: "rules",
+			data: `{"rules": [{"when": {"artist": "*dc"}, "suppress": true}]}`,
+			check: func(t *testing.T, o Options) {
+				if len(o.Rules) != 1 || !o.Rules[0].Suppress || o.Rules[0].When["artist"] != "*dc" {
+					t.Errorf("rules = %+v, want one suppressing *dc", o.Rules)
+				}
+			},
+		},
+		{name: "null", data: `null`},
+		{name: "unknown setting", data: `{"app_nmae": "Typo"}`, wantErr: `unknown field "app_nmae"`},
+		{name: "bad urgency", data: `{"urgency": "urgent"}`, wantErr: `unknown urgency "urgent"`},
+		{name: "newer version", data: `{"version": 99}`, wantErr: "newer than the supported version"},
+		{name: "bad version", data: `{"version": "1"}`, wantErr: "bad version"},
+		{name: "not JSON", data: `app_name = "Player"`, wantErr: "invalid config"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := parseConfig([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.check != nil {
+				tt.check(t, newOptions([]Option{DefaultOptions("Defaults"), WithConfig(config)}))
+			}
+		})
+	}
+}
+
+func TestMigrateConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name         string
+		data         string
+		wantMigrated bool
+	}{
+		{"unversioned", `{"app_name": "Player"}`, true},
+		{"current", `{"version": 1, "app_name": "Player"}`, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "notifications.json")
+			if err := os.WriteFile(path, []byte(tt.data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			migrated, err := MigrateConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if migrated != tt.wantMigrated {
+				t.Errorf("migrated = %v, want %v", migrated, tt.wantMigrated)
+			}
+			config, err := LoadConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if config.AppName == nil || *config.AppName != "Player" {
+				t.Errorf("app name = %v, want Player", config.AppName)
+			}
+
+			backup, err := os.ReadFile(path + ".bak")
+			switch {
+			case !tt.wantMigrated && !os.IsNotExist(err):
+				t.Errorf("backed up a current config: %v", err)
+			case tt.wantMigrated && string(backup) != tt.data:
+				t.Errorf("backup = %q, want the original %q (%v)", backup, tt.data, err)
+			}
+			if tt.wantMigrated && config.Version != configVersion {
+				t.Errorf("version = %d, want %d", config.Version, configVersion)
+			}
+		})
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("TESTPLAYER_APP_NAME", "Env Player")
+	t.Setenv("TESTPLAYER_TIMEOUT_MS", "2500")
+	t.Setenv("TESTPLAYER_NOTIFY_ON_PAUSE", "true")
+	t.Setenv("TESTPLAYER_FALLBACK_BACKENDS", "portal, notify-send,")
+
+	config, err := ConfigFromEnv("TESTPLAYER_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := newOptions([]Option{WithConfig(config)})
+	if o.AppName != "Env Player" || o.Timeout != 2500 || !o.NotifyOnPause {
+		t.Errorf("app name, timeout, notify on pause = %q, %d, %v; want Env Player, 2500, true", o.AppName, o.Timeout, o.NotifyOnPause)
+	}
+	if !slices.Equal(o.FallbackBackends, []string{"portal", "notify-send"}) {
+		t.Errorf("fallback backends = %q, want [portal notify-send]", o.FallbackBackends)
+	}
+
+	for name, value := range map[string]string{
+		"TESTPLAYER_TIMEOUT_MS":      "soon",
+		"TESTPLAYER_NOTIFY_ON_PAUSE": "sometimes",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv(name, value)
+			if _, err := ConfigFromEnv("TESTPLAYER_"); err == nil || !strings.Contains(err.Error(), name) {
+				t.Errorf("error = %v, want one naming %s", err, name)
+			}
+		})
+	}
+}
+
+func TestDumpConfigRoundTrip(t *testing.T) {
+	var sb strings.Builder
+	if err := DumpConfig(&sb, WithAppName("Player"), WithTimeout(3*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"version = 1\n", `app_name = "Player"` + "\n", "timeout_ms = 3000\n"} {
+		if !strings.Contains(sb.String(), want) {
+			t.Errorf("dump is missing %q:\n%s", want, sb.String())
+		}
+	}
+}
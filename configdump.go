@@ -0,0 +1,222 @@
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// configSetting is a scalar setting config files can hold, as DumpConfig
+// writes it and ConfigFromEnv reads it
+type configSetting struct {
+	key   string // Key in config files
+	doc   string
+	value any // string, bool, int32 or []string
+}
+
+// configSettings returns the scalar settings of options, in config file
+// order. Templates, the chapter template and rules are tables, written by
+// DumpConfig itself.
+func configSettings(o Options) []configSetting {
+	backend, fallbacks := o.Backend, o.FallbackBackends
+	if backend == "" {
+		backend = defaultBackend
+		if fallbacks == nil {
+			fallbacks = defaultFallbacks
+		}
+	}
+	return []configSetting{
+		{"app_name", "Application name shown in notifications", o.AppName},
+		{"icon", "Icon name or path", o.Icon},
+		{"timeout_ms", "Milliseconds before notifications expire (0 = never)", o.Timeout},
+		{"notify_on_pause", "Notify when playback is paused", o.NotifyOnPause},
+		{"clear_on_stop", "Close the track notification when playback stops", o.ClearOnStop},
+		{"replace_existing", "Replace the previous notification instead of stacking", o.ReplaceExisting},
+		{"desktop_entry", ".desktop file name, for grouping", o.DesktopEntry},
+		{"urgency", `"low", "normal" or "critical"`, urgencyNames[o.Urgency]},
+		{"transient", "Keep track notifications out of the history", o.Transient},
+		{"sound_name", "Sound theme name to play on track changes", o.SoundName},
+		{"sound_file", "Sound file to play on track changes", o.SoundFile},
+		{"suppress_sound", "Silence the daemon's sound on track changes", o.SuppressSound},
+		{"summary_template", "text/template for the title (empty: the track title)", o.SummaryTemplate},
+		{"body_template", "text/template for the body (empty: artist and album)", o.BodyTemplate},
+		{"locale", "Locale for templates (empty: from the environment)", o.Locale},
+		{"notify_on_chapter", "Notify on podcast chapter changes", o.NotifyOnChapter},
+		{"show_loudness", "Add the loudness and ReplayGain to the body", o.ShowLoudness},
+		{"show_progress", "Show how far into the track playback is as a gauge", o.ShowProgress},
+		{"show_origin", "Add where the track plays to the body", o.ShowOrigin},
+		{"squash_albums", "Turn consecutive tracks of an album into one updating notification", o.SquashAlbums},
+		{"suppress_during_presentation", "Skip notifications while presenting or sharing the screen", o.SuppressDuringPresentation},
+		{"respect_do_not_disturb", "Skip notifications in Do Not Disturb mode", o.RespectDoNotDisturb},
+		{"do_not_disturb_allow_errors", "Show errors despite Do Not Disturb", o.DoNotDisturbAllowErrors},
+		{"backend", "Registered backend to use", backend},
+		{"fallback_backends", "Backends tried in order if backend can't be opened", fallbacks},
+	}
+}
+
+// DumpConfig writes the effective settings as annotated TOML: the
+// defaults, with options applied in order, e.g. WithConfig for the
+// config file and then for ConfigFromEnv. It covers everything config
+// files can hold, so users can see what they can change. Config files
+// themselves stay JSON.
+func DumpConfig(w io.Writer, opts ...Option) error {
+	o := newOptions(opts)
+
+	var sb strings.Builder
+	sb.WriteString("# Effective notification settings\n\n")
+	sb.WriteString("# Config file format version\n")
+	fmt.Fprintf(&sb, "version = %d\n", configVersion)
+	for _, setting := range configSettings(o) {
+		fmt.Fprintf(&sb, "\n# %s\n%s = %s\n", setting.doc, setting.key, tomlValue(setting.value))
+	}
+
+	sb.WriteString("\n# Templates by locale, overriding summary_template and body_template\n")
+	locales := make([]string, 0, len(o.Templates))
+	for locale := range o.Templates {
+		locales = append(locales, locale)
+	}
+	slices.Sort(locales)
+	for _, locale := range locales {
+		set := o.Templates[locale]
+		fmt.Fprintf(&sb, "[templates.%s]\nsummary = %s\nbody = %s\n", tomlKey(locale), tomlString(set.Summary), tomlString(set.Body))
+	}
+
+	sb.WriteString("\n# Templates for chapter notifications (empty: the built-in ones)\n")
+	fmt.Fprintf(&sb, "[chapter_template]\nsummary = %s\nbody = %s\n", tomlString(o.ChapterTemplate.Summary), tomlString(o.ChapterTemplate.Body))
+
+	sb.WriteString("\n# Rules changing how matching tracks are shown, in order\n")
+	for _, rule := range o.Rules {
+		sb.WriteString("[[rules]]\n")
+		fields := make([]string, 0, len(rule.When))
+		for field := range rule.When {
+			fields = append(fields, field)
+		}
+		slices.Sort(fields)
+		when := make([]string, len(fields))
+		for i, field := range fields {
+			when[i] = tomlKey(field) + " = " + tomlString(rule.When[field])
+		}
+		fmt.Fprintf(&sb, "when = { %s }\n", strings.Join(when, ", "))
+		if rule.Suppress {
+			sb.WriteString("suppress = true\n")
+		}
+		if rule.Urgency != "" {
+			fmt.Fprintf(&sb, "urgency = %s\n", tomlString(rule.Urgency))
+		}
+		if rule.Timeout != nil {
+			fmt.Fprintf(&sb, "timeout_ms = %d\n", *rule.Timeout)
+		}
+		if rule.Icon != "" {
+			fmt.Fprintf(&sb, "icon = %s\n", tomlString(rule.Icon))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// tomlValue formats a setting's value
+func tomlValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return tomlString(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int32:
+		return strconv.Itoa(int(v))
+	case []string:
+		quoted := make([]string, len(v))
+		for i, s := range v {
+			quoted[i] = tomlString(s)
+		}
+		return "[" + strings.Join(quoted, ", ") + "]"
+	}
+	panic(fmt.Sprintf("notifications: no TOML form for %T", value))
+}
+
+// tomlString quotes a TOML basic string
+func tomlString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&sb, `\u%04X`, r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+// tomlKey returns key bare if TOML allows it, and quoted otherwise
+func tomlKey(key string) string {
+	bare := key != ""
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			bare = false
+		}
+	}
+	if bare {
+		return key
+	}
+	return tomlString(key)
+}
+
+// ConfigFromEnv reads settings from environment variables named prefix
+// plus the config file key in upper case, e.g. MYPLAYER_TIMEOUT_MS=3000
+// for prefix "MYPLAYER_". Lists are comma-separated. Only scalar settings
+// and lists can be set this way; templates and rules need a config file.
+func ConfigFromEnv(prefix string) (Config, error) {
+	settings := make(map[string]any)
+	for _, setting := range configSettings(DefaultOptions("")) {
+		name := prefix + strings.ToUpper(setting.key)
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			continue
+		}
+		switch setting.value.(type) {
+		case string:
+			settings[setting.key] = value
+		case bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid %s: %q is not a boolean", name, value)
+			}
+			settings[setting.key] = b
+		case int32:
+			i, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid %s: %q is not a number", name, value)
+			}
+			settings[setting.key] = i
+		case []string:
+			list := []string{}
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			settings[setting.key] = list
+		}
+	}
+
+	settings["version"] = configVersion
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return Config{}, err
+	}
+	return parseConfig(data)
+}
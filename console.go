@@ -0,0 +1,73 @@
+package notifications
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	Register("terminal", newTerminalBackend)
+	Register("file", newFileBackend)
+}
+
+// writerBackend prints notifications as lines of text, for sessions
+// without a desktop (pure console, ssh without forwarding)
+type writerBackend struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer // nil for stderr
+	stamp  bool      // Prefix lines with the time
+}
+
+// newTerminalBackend prints notifications to stderr
+func newTerminalBackend(config BackendConfig) (Backend, error) {
+	return &writerBackend{w: os.Stderr}, nil
+}
+
+// newFileBackend appends notifications to Options.LogFile
+func newFileBackend(config BackendConfig) (Backend, error) {
+	path := config.Options.LogFile
+	if path == "" {
+		return nil, fmt.Errorf("file backend needs Options.LogFile")
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open notification log: %w", err)
+	}
+	return &writerBackend{w: f, closer: f, stamp: true}, nil
+}
+
+// Send writes the notification as one line
+func (b *writerBackend) Send(note *Notification) (uint32, error) {
+	line := "♪ " + note.Summary
+	if note.Body != "" {
+		line += " — " + strings.ReplaceAll(note.Body, "\n", " · ")
+	}
+	if b.stamp {
+		line = time.Now().Format(time.RFC3339) + " " + line
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, err := fmt.Fprintln(b.w, line); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// Capabilities reports no features; lines can't be replaced or clicked
+func (b *writerBackend) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+// Close closes the log file
+func (b *writerBackend) Close() error {
+	if b.closer == nil {
+		return nil
+	}
+	return b.closer.Close()
+}
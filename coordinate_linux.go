@@ -0,0 +1,88 @@
+//go:build linux
+
+package notifications
+
+import (
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// coordinationName is the bus name instances take turns owning. Every
+// notifier with Options.Coordinate queues for it, and only its owner shows
+// track notifications.
+const coordinationName = "io.github.go_music_players.Notifications"
+
+// coordinator queues for coordinationName on its own session bus
+// connection, so it works whichever backend delivers notifications
+type coordinator struct {
+	conn *dbus.Conn
+
+	mu      sync.Mutex
+	primary bool
+}
+
+// coordinate queues for the coordination name. It returns nil when the
+// session bus isn't available, and a nil coordinator is always primary.
+func coordinate() *coordinator {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil
+	}
+	c := &coordinator{conn: conn}
+
+	// NameAcquired and NameLost are sent to us directly, so no match rule
+	// is needed; subscribe before queueing so neither is missed
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	reply, err := conn.RequestName(coordinationName, 0)
+	if err != nil {
+		conn.Close()
+		return nil
+	}
+	c.primary = reply == dbus.RequestNameReplyPrimaryOwner
+
+	go func() {
+		// The channel is closed when the connection is closed
+		for signal := range signals {
+			if len(signal.Body) < 1 || signal.Body[0] != coordinationName {
+				continue
+			}
+			switch signal.Name {
+			case "org.freedesktop.DBus.NameAcquired":
+				c.setPrimary(true)
+			case "org.freedesktop.DBus.NameLost":
+				c.setPrimary(false)
+			}
+		}
+	}()
+
+	return c
+}
+
+// setPrimary records whether this instance owns the name
+func (c *coordinator) setPrimary(primary bool) {
+	c.mu.Lock()
+	c.primary = primary
+	c.mu.Unlock()
+}
+
+// active reports whether this instance shows notifications. An instance
+// that lost the bus connection stops deferring to the others.
+func (c *coordinator) active() bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.primary || !c.conn.Connected()
+}
+
+// close gives up the name, passing it to the next instance in the queue
+func (c *coordinator) close() error {
+	if c == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package notifications
+
+// coordinator is only available through the session bus on Linux
+type coordinator struct{}
+
+// coordinate returns nil, which is always primary
+func coordinate() *coordinator {
+	return nil
+}
+
+// active reports whether this instance shows notifications
+func (c *coordinator) active() bool {
+	return true
+}
+
+// close gives up coordination
+func (c *coordinator) close() error {
+	return nil
+}
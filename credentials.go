@@ -0,0 +1,190 @@
+package notifications
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// ErrNoCredential means a CredentialProvider has no secret by that name
+var ErrNoCredential = errors.New("credential not found")
+
+// CredentialProvider looks up the secrets remote backends need, such as
+// push endpoints and tokens, so they don't have to sit in plaintext config
+type CredentialProvider interface {
+	// Credential returns the secret stored under name, or an error
+	// wrapping ErrNoCredential if there is none
+	Credential(name string) (string, error)
+}
+
+// credential looks name up in Options.Credentials, returning "" if there
+// is no provider or no such secret
+func (o *Options) credential(name string) (string, error) {
+	if o.Credentials == nil {
+		return "", nil
+	}
+	secret, err := o.Credentials.Credential(name)
+	if errors.Is(err, ErrNoCredential) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up %s: %w", name, err)
+	}
+	return secret, nil
+}
+
+// chainedCredentials tries providers in order
+type chainedCredentials []CredentialProvider
+
+// ChainCredentials looks secrets up in each provider in order, e.g. the
+// environment before the keyring
+func ChainCredentials(providers ...CredentialProvider) CredentialProvider {
+	return chainedCredentials(providers)
+}
+
+// Credential returns the secret from the first provider that has it
+func (c chainedCredentials) Credential(name string) (string, error) {
+	for _, provider := range c {
+		secret, err := provider.Credential(name)
+		if !errors.Is(err, ErrNoCredential) {
+			return secret, err
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrNoCredential, name)
+}
+
+// envCredentials reads secrets from environment variables
+type envCredentials string
+
+// EnvCredentials reads secrets from environment variables named prefix
+// plus the secret's name in upper case, with dashes as underscores: with
+// prefix "MYPLAYER_", "unifiedpush-token" is MYPLAYER_UNIFIEDPUSH_TOKEN
+func EnvCredentials(prefix string) CredentialProvider {
+	return envCredentials(prefix)
+}
+
+// Credential reads the variable for name
+func (prefix envCredentials) Credential(name string) (string, error) {
+	variable := string(prefix) + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	if secret, ok := os.LookupEnv(variable); ok && secret != "" {
+		return secret, nil
+	}
+	return "", fmt.Errorf("%w: $%s is not set", ErrNoCredential, variable)
+}
+
+// fileCredentials reads secrets from files in a directory
+type fileCredentials string
+
+// FileCredentials reads each secret from the file of its name in dir, such
+// as a systemd credentials directory ($CREDENTIALS_DIRECTORY). Files
+// readable by other users are refused outside Windows.
+func FileCredentials(dir string) CredentialProvider {
+	return fileCredentials(dir)
+}
+
+// Credential reads the file for name, without its trailing newline
+func (dir fileCredentials) Credential(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid credential name %q", name)
+	}
+	path := filepath.Join(string(dir), name)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: %s", ErrNoCredential, path)
+	}
+	if err != nil {
+		return "", err
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		return "", fmt.Errorf("%s is readable by other users; chmod 600 it", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// commandCredentials runs a command printing secrets
+type commandCredentials struct {
+	name string
+	args []string
+}
+
+// CommandCredentials runs a password manager to print each secret, e.g.
+// CommandCredentials("pass", "show", "myplayer/{name}"). "{name}" in the
+// arguments is replaced by the secret's name; without it, the name is
+// passed as the last argument. A command that fails or prints nothing has
+// no such secret.
+func CommandCredentials(name string, args ...string) CredentialProvider {
+	return &commandCredentials{name: name, args: args}
+}
+
+// Credential runs the command for name, returning its first output line
+func (c *commandCredentials) Credential(name string) (string, error) {
+	args := make([]string, len(c.args))
+	substituted := false
+	for i, arg := range c.args {
+		args[i] = strings.ReplaceAll(arg, "{name}", name)
+		substituted = substituted || args[i] != arg
+	}
+	if !substituted {
+		args = append(args, name)
+	}
+	return runCredentialCommand(c.name, args...)
+}
+
+// runCredentialCommand runs a command printing a secret. A command that
+// isn't installed has no secrets, so a chain can go on to the next
+// provider.
+func runCredentialCommand(name string, args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && stderr.Len() > 0:
+		return "", fmt.Errorf("%w: %s: %s", ErrNoCredential, name, strings.TrimSpace(stderr.String()))
+	case errors.As(err, &exitErr):
+		return "", fmt.Errorf("%w: %s: %w", ErrNoCredential, name, err)
+	case errors.Is(err, exec.ErrNotFound):
+		return "", fmt.Errorf("%w: %w", ErrNoCredential, err)
+	case err != nil:
+		return "", fmt.Errorf("%s: %w", name, err)
+	}
+	secret, _, _ := strings.Cut(string(out), "\n")
+	if secret = strings.TrimRight(secret, "\r"); secret == "" {
+		return "", fmt.Errorf("%w: %s printed nothing", ErrNoCredential, name)
+	}
+	return secret, nil
+}
+
+// KeyringCredentials reads secrets from the desktop keyring, under the
+// service and the secret's name: on Linux through libsecret's secret-tool
+// (attributes "service" and "name", as stored with `secret-tool store
+// --label=... service myplayer name unifiedpush-token`), on macOS from the
+// login keychain's generic passwords (service and account). Other
+// platforms have no keyring, so it finds nothing there.
+func KeyringCredentials(service string) CredentialProvider {
+	return keyringCredentials(service)
+}
+
+// keyringCredentials reads secrets from the desktop keyring
+type keyringCredentials string
+
+// Credential looks name up in the keyring
+func (service keyringCredentials) Credential(name string) (string, error) {
+	switch runtime.GOOS {
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return runCredentialCommand("secret-tool", "lookup", "service", string(service), "name", name)
+	case "darwin":
+		return runCredentialCommand("security", "find-generic-password", "-s", string(service), "-a", name, "-w")
+	}
+	return "", fmt.Errorf("%w: no keyring support on %s", ErrNoCredential, runtime.GOOS)
+}
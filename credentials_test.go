@@ -0,0 +1,219 @@
+package notifications_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/go-music-players/notifications"
+)
+
+// writeSecret writes a credential file in dir
+func writeSecret(t *testing.T, dir, name, secret string, perm os.FileMode) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(secret), perm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, perm); err != nil { // Regardless of the umask
+		t.Fatal(err)
+	}
+}
+
+// checkCredential looks name up, comparing with the secret or error
+// wanted. wantErr is a substring of the error, which must wrap
+// ErrNoCredential if noCredential.
+func checkCredential(t *testing.T, provider notifications.CredentialProvider, name, want, wantErr string, noCredential bool) {
+	t.Helper()
+	secret, err := provider.Credential(name)
+	switch {
+	case wantErr == "" && err != nil:
+		t.Fatalf("Credential(%q): %v", name, err)
+	case wantErr != "" && (err == nil || !strings.Contains(err.Error(), wantErr)):
+		t.Fatalf("Credential(%q) = %q, %v, want an error containing %q", name, secret, err, wantErr)
+	case errors.Is(err, notifications.ErrNoCredential) != noCredential:
+		t.Errorf("Credential(%q): %v wrapping ErrNoCredential: %v, want %v", name, err, !noCredential, noCredential)
+	case secret != want:
+		t.Errorf("Credential(%q) = %q, want %q", name, secret, want)
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	const name = "unifiedpush-token"
+	for _, tt := range []struct {
+		name         string
+		env          string // Value of TEST_UNIFIEDPUSH_TOKEN, if set
+		file         string // Content of the credential file, if any
+		perm         os.FileMode
+		provider     func(dir string) notifications.CredentialProvider
+		lookup       string // Name to look up (default: name)
+		want         string
+		wantErr      string
+		noCredential bool // Whether the error wraps ErrNoCredential
+	}{
+		{
+			name:     "env",
+			env:      "s3cret",
+			provider: func(string) notifications.CredentialProvider { return notifications.EnvCredentials("TEST_") },
+			want:     "s3cret",
+		},
+		{
+			name:         "env unset",
+			provider:     func(string) notifications.CredentialProvider { return notifications.EnvCredentials("TEST_") },
+			wantErr:      "$TEST_UNIFIEDPUSH_TOKEN is not set",
+			noCredential: true,
+		},
+		{
+			name:     "file",
+			file:     "s3cret\n",
+			perm:     0o600,
+			provider: notifications.FileCredentials,
+			want:     "s3cret",
+		},
+		{
+			name:     "file with CRLF",
+			file:     "s3cret\r\n",
+			perm:     0o400,
+			provider: notifications.FileCredentials,
+			want:     "s3cret",
+		},
+		{
+			name:         "file missing",
+			provider:     notifications.FileCredentials,
+			wantErr:      name,
+			noCredential: true,
+		},
+		{
+			name:     "file readable by others",
+			file:     "s3cret\n",
+			perm:     0o644,
+			provider: notifications.FileCredentials,
+			wantErr:  "readable by other users",
+		},
+		{
+			name:     "file outside the directory",
+			provider: notifications.FileCredentials,
+			lookup:   "../" + name,
+			wantErr:  "invalid credential name",
+		},
+		{
+			name: "chain falls through",
+			file: "from file\n",
+			perm: 0o600,
+			provider: func(dir string) notifications.CredentialProvider {
+				return notifications.ChainCredentials(notifications.EnvCredentials("TEST_"), notifications.FileCredentials(dir))
+			},
+			want: "from file",
+		},
+		{
+			name: "chain prefers the first",
+			env:  "from env",
+			file: "from file\n",
+			perm: 0o600,
+			provider: func(dir string) notifications.CredentialProvider {
+				return notifications.ChainCredentials(notifications.EnvCredentials("TEST_"), notifications.FileCredentials(dir))
+			},
+			want: "from env",
+		},
+		{
+			name: "chain stops at failures",
+			env:  "from env",
+			file: "from file\n",
+			perm: 0o644,
+			provider: func(dir string) notifications.CredentialProvider {
+				return notifications.ChainCredentials(notifications.FileCredentials(dir), notifications.EnvCredentials("TEST_"))
+			},
+			wantErr: "readable by other users",
+		},
+		{
+			name: "chain finds nothing",
+			provider: func(dir string) notifications.CredentialProvider {
+				return notifications.ChainCredentials(notifications.EnvCredentials("TEST_"), notifications.FileCredentials(dir))
+			},
+			wantErr:      name,
+			noCredential: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.perm&0o077 != 0 && runtime.GOOS == "windows" {
+				t.Skip("Windows has no file permissions to check")
+			}
+			if tt.env != "" {
+				t.Setenv("TEST_UNIFIEDPUSH_TOKEN", tt.env)
+			} else {
+				t.Setenv("TEST_UNIFIEDPUSH_TOKEN", "")
+				os.Unsetenv("TEST_UNIFIEDPUSH_TOKEN")
+			}
+			dir := t.TempDir()
+			if tt.file != "" {
+				writeSecret(t, dir, name, tt.file, tt.perm)
+			}
+			lookup := tt.lookup
+			if lookup == "" {
+				lookup = name
+			}
+			checkCredential(t, tt.provider(dir), lookup, tt.want, tt.wantErr, tt.noCredential)
+		})
+	}
+}
+
+func TestCommandCredentials(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("no sh to run commands with")
+	}
+	for _, tt := range []struct {
+		name         string
+		provider     notifications.CredentialProvider
+		want         string
+		wantErr      string
+		noCredential bool
+	}{
+		{
+			name:     "name appended",
+			provider: notifications.CommandCredentials("sh", "-c", `echo "secret for $0"; echo second line`),
+			want:     "secret for unifiedpush-token",
+		},
+		{
+			name:     "name substituted",
+			provider: notifications.CommandCredentials("sh", "-c", `echo "$1"`, "sh", "myplayer/{name}"),
+			want:     "myplayer/unifiedpush-token",
+		},
+		{
+			name:         "failure",
+			provider:     notifications.CommandCredentials("sh", "-c", `echo "$0 is not in the password store" >&2; exit 1`),
+			wantErr:      "unifiedpush-token is not in the password store",
+			noCredential: true,
+		},
+		{
+			name:         "failure without output",
+			provider:     notifications.CommandCredentials("sh", "-c", "exit 1"),
+			wantErr:      "exit status 1",
+			noCredential: true,
+		},
+		{
+			name:         "prints nothing",
+			provider:     notifications.CommandCredentials("sh", "-c", "true"),
+			wantErr:      "printed nothing",
+			noCredential: true,
+		},
+		{
+			name:         "not installed",
+			provider:     notifications.CommandCredentials("notifications-test-no-such-command"),
+			wantErr:      "notifications-test-no-such-command",
+			noCredential: true,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			checkCredential(t, tt.provider, "unifiedpush-token", tt.want, tt.wantErr, tt.noCredential)
+		})
+	}
+}
+
+func TestKeyringCredentialsWithoutKeyring(t *testing.T) {
+	t.Setenv("PATH", t.TempDir()) // Neither secret-tool nor security
+	checkCredential(t, notifications.KeyringCredentials("myplayer"), "unifiedpush-token", "", "credential not found", true)
+}
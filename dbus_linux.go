@@ -0,0 +1,590 @@
+//go:build linux
+
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	notificationsInterface = "org.freedesktop.Notifications"
+	notificationsPath      = "/org/freedesktop/Notifications"
+)
+
+// maxMessageSize is the largest message the D-Bus specification allows.
+// The rest of a notification is tiny next to raw pixels, but leave some
+// headroom for it.
+const (
+	maxMessageSize  = 128 << 20
+	messageHeadroom = 64 << 10
+)
+
+// defaultBackend is used when Options.Backend is empty
+const defaultBackend = "dbus"
+
+// defaultFallbacks are tried when the default backend can't be opened.
+// Sandboxed apps usually can't reach the daemon but can reach the portal.
+var defaultFallbacks = []string{"portal", "notify-send"}
+
+func init() {
+	Register("dbus", newDBusBackend)
+}
+
+// dbusBackend delivers notifications via org.freedesktop.Notifications
+type dbusBackend struct {
+	config BackendConfig
+
+	// The connection is replaced when the session bus goes away, unless
+	// Options.DisableReconnect is set
+	connMu sync.Mutex
+	conn   *dbus.Conn
+	closed bool
+
+	// Daemon properties, cached until the daemon restarts (the bus name
+	// changes owner) since they would otherwise cost a round trip per
+	// notification
+	cacheMu sync.Mutex
+	cached  bool
+	rawCaps []string
+	caps    Capabilities
+	info    ServerInfo
+	hasInfo bool
+
+	// With action-icons the daemon reports the icon name rather than the
+	// action ID, so keep the mapping back for each open notification
+	mu         sync.Mutex
+	actionKeys map[uint32]map[string]string
+
+	// The last image fitted to MaxImageBytes, as updates re-send the same
+	// art and downscaling it each time would dominate the cost of a send
+	fitMu     sync.Mutex
+	fitSource *image.NRGBA
+	fitResult *image.NRGBA
+
+	dndMu      sync.Mutex
+	dnd        bool      // Whether Do Not Disturb is on
+	dndChecked time.Time // When dnd was read
+}
+
+// newDBusBackend connects to the session bus and checks that a
+// notification daemon is running
+func newDBusBackend(config BackendConfig) (Backend, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to session bus: %w", diagnoseConnect(err))
+	}
+
+	b := &dbusBackend{
+		conn:       conn,
+		config:     config,
+		actionKeys: make(map[uint32]map[string]string),
+	}
+
+	// Test that notifications are available
+	b.cacheMu.Lock()
+	err = b.refresh()
+	b.cacheMu.Unlock()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("D-Bus notifications not available: %w", diagnoseCall(err))
+	}
+
+	if err := b.listen(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to subscribe to notification signals: %w", err)
+	}
+
+	return b, nil
+}
+
+// parseCapabilities maps the daemon's capability strings onto Capabilities
+func parseCapabilities(serverCaps []string) Capabilities {
+	caps := Capabilities{
+		Replacement: true, // replaces_id is part of the core spec
+		Progress:    true, // No capability advertises the value hint; daemons without it ignore it
+	}
+	for _, c := range serverCaps {
+		switch c {
+		case "actions":
+			caps.Actions = true
+		case "action-icons":
+			caps.ActionIcons = true
+		case "body-markup":
+			caps.Markup = true
+		case "body-images", "icon-static", "icon-multi":
+			caps.Images = true
+		}
+	}
+	return caps
+}
+
+// allHaveIcons reports whether there are buttons and every one has an
+// icon. The default action is a click on the body, not a button.
+func allHaveIcons(actions []Action) bool {
+	buttons := 0
+	for _, action := range actions {
+		if action.ID == "default" {
+			continue
+		}
+		if action.Icon == "" {
+			return false
+		}
+		buttons++
+	}
+	return buttons > 0
+}
+
+// Send calls Notify on the daemon. If the session bus went away, it
+// reconnects and tries once more, as a new notification.
+func (b *dbusBackend) Send(note *Notification) (uint32, error) {
+	conn := b.bus()
+	id, err := b.sendOn(conn, note)
+	if err == nil || conn.Connected() || b.config.Options.DisableReconnect {
+		return id, err
+	}
+	b.log().Debug("session bus connection lost, reconnecting", "err", err)
+	if conn, err = b.reconnect(conn); err != nil {
+		return 0, fmt.Errorf("lost the session bus: %w", err)
+	}
+	retry := *note
+	retry.ReplacesID = 0 // IDs from before don't survive the bus
+	return b.sendOn(conn, &retry)
+}
+
+// sendOn calls Notify over conn
+func (b *dbusBackend) sendOn(conn *dbus.Conn, note *Notification) (uint32, error) {
+	obj := conn.Object(notificationsInterface, notificationsPath)
+
+	// Hints
+	hints := make(map[string]dbus.Variant, 4)
+	mode := b.imageMode()
+	if note.Image != nil && mode != ImagePath {
+		if img := b.fitImage(note.Image); img != nil {
+			hints["image-data"] = imageDataHint(img)
+		}
+	}
+	if note.ImagePath != "" && mode != ImageData {
+		hints["image-path"] = dbus.MakeVariant("file://" + note.ImagePath)
+	}
+
+	// Actions are sent as alternating key/label pairs. With action-icons,
+	// the daemon interprets the key as an icon name instead.
+	useIcons := b.Capabilities().ActionIcons && allHaveIcons(note.Actions)
+	actions := make([]string, 0, 2*len(note.Actions))
+	keys := make(map[string]string, len(note.Actions))
+	for _, action := range note.Actions {
+		key := action.ID
+		if useIcons && action.ID != "default" {
+			key = action.Icon
+		}
+		keys[key] = action.ID
+		actions = append(actions, key, action.Label)
+	}
+	if useIcons {
+		hints["action-icons"] = dbus.MakeVariant(true)
+	}
+	if note.DesktopEntry != "" {
+		hints["desktop-entry"] = dbus.MakeVariant(note.DesktopEntry)
+	}
+	if note.Urgency != UrgencyNormal {
+		hints["urgency"] = dbus.MakeVariant(urgencyLevel(note.Urgency))
+	}
+	if note.Transient {
+		hints["transient"] = dbus.MakeVariant(true)
+	}
+	if note.Resident {
+		hints["resident"] = dbus.MakeVariant(true)
+	}
+	if note.Progress >= 0 {
+		hints["value"] = dbus.MakeVariant(int32(note.Progress))
+	}
+	if note.SoundName != "" {
+		hints["sound-name"] = dbus.MakeVariant(note.SoundName)
+	}
+	if note.SoundFile != "" {
+		hints["sound-file"] = dbus.MakeVariant(note.SoundFile)
+	}
+	if note.SuppressSound {
+		hints["suppress-sound"] = dbus.MakeVariant(true)
+	}
+
+	// Call Notify
+	ctx := context.Background()
+	if timeout := b.config.Options.Retry.AttemptTimeout; timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	call := obj.CallWithContext(
+		ctx,
+		notificationsInterface+".Notify",
+		0,
+		note.AppName,    // app_name
+		note.ReplacesID, // replaces_id (0 = new notification, >0 = replace)
+		note.Icon,       // app_icon
+		note.Summary,    // summary
+		note.Body,       // body
+		actions,         // actions
+		hints,           // hints
+		note.Timeout,    // expire_timeout (-1 = default, 0 = never, >0 = milliseconds)
+	)
+	if call.Err != nil {
+		return 0, diagnoseCall(call.Err)
+	}
+
+	var id uint32
+	if len(call.Body) > 0 {
+		id, _ = call.Body[0].(uint32)
+	}
+
+	b.mu.Lock()
+	b.actionKeys[id] = keys
+	b.mu.Unlock()
+
+	return id, nil
+}
+
+// imageQuirks are daemons known to handle one way of passing images better
+var imageQuirks = map[string]ImageMode{
+	"notify-osd": ImageData, // Ignores image-path
+	"Plasma":     ImagePath, // Loads and scales the file itself, skipping the pixel copy over the bus
+}
+
+// imageMode returns how to pass album art to this daemon
+func (b *dbusBackend) imageMode() ImageMode {
+	if mode := b.config.Options.ImageMode; mode != ImageAuto {
+		return mode
+	}
+
+	// Raw pixels work everywhere, including sandboxed daemons; the path
+	// is for daemons that prefer loading the file themselves
+	info, err := b.ServerInfo()
+	if err != nil {
+		return ImageBoth
+	}
+	if mode, ok := imageQuirks[info.Name]; ok {
+		return mode
+	}
+	return ImageBoth
+}
+
+// fitImage fits img to the size limit, reusing the previous result
+func (b *dbusBackend) fitImage(img *image.NRGBA) *image.NRGBA {
+	b.fitMu.Lock()
+	defer b.fitMu.Unlock()
+
+	if img != b.fitSource {
+		b.fitSource = img
+		b.fitResult = fitImage(img, b.maxImageBytes())
+	}
+	return b.fitResult
+}
+
+// maxImageBytes returns how large the image-data hint may be
+func (b *dbusBackend) maxImageBytes() int {
+	limit := maxMessageSize - messageHeadroom
+	if configured := b.config.Options.MaxImageBytes; configured > 0 && configured < limit {
+		limit = configured
+	}
+	return limit
+}
+
+// Dismiss calls CloseNotification on the daemon
+func (b *dbusBackend) Dismiss(id uint32) error {
+	obj := b.bus().Object(notificationsInterface, notificationsPath)
+	if err := obj.Call(notificationsInterface+".CloseNotification", 0, id).Err; err != nil {
+		return diagnoseCall(err)
+	}
+	return nil
+}
+
+// refresh fetches the daemon's capabilities. cacheMu must be held.
+func (b *dbusBackend) refresh() error {
+	obj := b.bus().Object(notificationsInterface, notificationsPath)
+	call := obj.Call(notificationsInterface+".GetCapabilities", 0)
+	if call.Err != nil {
+		return diagnoseCall(call.Err)
+	}
+
+	var serverCaps []string
+	if len(call.Body) > 0 {
+		serverCaps, _ = call.Body[0].([]string)
+	}
+	if serverCaps == nil {
+		serverCaps = []string{}
+	}
+
+	b.rawCaps = serverCaps
+	b.caps = parseCapabilities(serverCaps)
+	b.cached = true
+	b.hasInfo = false
+
+	// Some daemons show fewer buttons than they are sent
+	if info, err := b.fetchServerInfo(); err == nil {
+		b.info, b.hasInfo = info, true
+		b.caps.MaxActions = actionLimits[info.Name]
+	}
+	b.log().Debug("notification daemon capabilities", "daemon", b.info.Name, "version", b.info.Version, "raw", serverCaps, "capabilities", b.caps)
+	return nil
+}
+
+// actionLimits are daemons known to show only some of the actions sent
+var actionLimits = map[string]int{
+	"gnome-shell": 3,
+}
+
+// invalidate drops the cached daemon properties
+func (b *dbusBackend) invalidate() {
+	b.cacheMu.Lock()
+	b.cached = false
+	b.hasInfo = false
+	b.cacheMu.Unlock()
+}
+
+// Capabilities returns the features supported by the daemon. If they
+// can't be refreshed after a restart, the previous ones are kept.
+func (b *dbusBackend) Capabilities() Capabilities {
+	b.cacheMu.Lock()
+	defer b.cacheMu.Unlock()
+
+	if !b.cached {
+		b.refresh()
+	}
+	return b.caps
+}
+
+// ListCapabilities returns the daemon's capability strings
+func (b *dbusBackend) ListCapabilities() ([]string, error) {
+	b.cacheMu.Lock()
+	defer b.cacheMu.Unlock()
+
+	if !b.cached {
+		if err := b.refresh(); err != nil {
+			return nil, err
+		}
+	}
+	return append([]string{}, b.rawCaps...), nil
+}
+
+// ServerInfo returns the daemon's name and version
+func (b *dbusBackend) ServerInfo() (ServerInfo, error) {
+	b.cacheMu.Lock()
+	defer b.cacheMu.Unlock()
+
+	if b.hasInfo {
+		return b.info, nil
+	}
+
+	info, err := b.fetchServerInfo()
+	if err != nil {
+		return ServerInfo{}, err
+	}
+	b.info, b.hasInfo = info, true
+	return info, nil
+}
+
+// fetchServerInfo calls GetServerInformation
+func (b *dbusBackend) fetchServerInfo() (ServerInfo, error) {
+	var info ServerInfo
+	obj := b.bus().Object(notificationsInterface, notificationsPath)
+	err := obj.Call(notificationsInterface+".GetServerInformation", 0).
+		Store(&info.Name, &info.Vendor, &info.Version, &info.SpecVersion)
+	return info, err
+}
+
+// Close closes the D-Bus connection
+func (b *dbusBackend) Close() error {
+	b.connMu.Lock()
+	defer b.connMu.Unlock()
+	b.closed = true
+	return b.conn.Close()
+}
+
+// bus returns the current connection
+func (b *dbusBackend) bus() *dbus.Conn {
+	b.connMu.Lock()
+	defer b.connMu.Unlock()
+	return b.conn
+}
+
+// log returns the logger from the options
+func (b *dbusBackend) log() *slog.Logger {
+	return b.config.Options.logger()
+}
+
+// reconnect replaces the lost connection stale with a new one, unless
+// another send already did
+func (b *dbusBackend) reconnect(stale *dbus.Conn) (*dbus.Conn, error) {
+	b.connMu.Lock()
+	if b.closed {
+		b.connMu.Unlock()
+		return nil, dbus.ErrClosed
+	}
+	if b.conn != stale {
+		conn := b.conn
+		b.connMu.Unlock()
+		return conn, nil
+	}
+
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		b.connMu.Unlock()
+		return nil, diagnoseConnect(err)
+	}
+	if err := b.listen(conn); err != nil {
+		b.connMu.Unlock()
+		conn.Close()
+		return nil, err
+	}
+	b.conn = conn
+	b.connMu.Unlock()
+	stale.Close()
+
+	b.log().Debug("reconnected to the session bus")
+	b.invalidate()
+	b.forgetAll()
+	return conn, nil
+}
+
+// forgetAll reports every open notification as closed, when the daemon
+// or the bus restarted and took them along. The notifier then stops
+// replacing IDs the new daemon doesn't know.
+func (b *dbusBackend) forgetAll() {
+	b.mu.Lock()
+	ids := make([]uint32, 0, len(b.actionKeys))
+	for id := range b.actionKeys {
+		ids = append(ids, id)
+	}
+	b.actionKeys = make(map[uint32]map[string]string)
+	b.mu.Unlock()
+
+	if b.config.OnClosed == nil {
+		return
+	}
+	for _, id := range ids {
+		b.config.OnClosed(id, CloseUndefined)
+	}
+}
+
+// urgencyLevel maps an Urgency to the byte value defined by the spec
+func urgencyLevel(u Urgency) byte {
+	switch u {
+	case UrgencyLow:
+		return 0
+	case UrgencyCritical:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// listen subscribes to the daemon's signals and dispatches them until the
+// connection is closed
+func (b *dbusBackend) listen(conn *dbus.Conn) error {
+	for _, member := range []string{"ActionInvoked", "NotificationClosed"} {
+		err := conn.AddMatchSignal(
+			dbus.WithMatchObjectPath(notificationsPath),
+			dbus.WithMatchInterface(notificationsInterface),
+			dbus.WithMatchMember(member),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// The daemon restarting means its capabilities may have changed
+	err := conn.AddMatchSignal(
+		dbus.WithMatchSender("org.freedesktop.DBus"),
+		dbus.WithMatchInterface("org.freedesktop.DBus"),
+		dbus.WithMatchMember("NameOwnerChanged"),
+		dbus.WithMatchArg(0, notificationsInterface),
+	)
+	if err != nil {
+		return err
+	}
+
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	go func() {
+		// The channel is closed when the connection is closed
+		for signal := range signals {
+			switch signal.Name {
+			case notificationsInterface + ".ActionInvoked":
+				b.actionInvoked(signal)
+			case notificationsInterface + ".NotificationClosed":
+				b.notificationClosed(signal)
+			case "org.freedesktop.DBus.NameOwnerChanged":
+				b.daemonChanged(signal)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// daemonChanged handles the daemon starting or quitting. Its capabilities
+// may have changed, and one that quit took its notifications along.
+func (b *dbusBackend) daemonChanged(signal *dbus.Signal) {
+	b.invalidate()
+
+	var oldOwner, newOwner string
+	if len(signal.Body) >= 3 {
+		oldOwner, _ = signal.Body[1].(string)
+		newOwner, _ = signal.Body[2].(string)
+	}
+	b.log().Debug("notification daemon changed", "old_owner", oldOwner, "new_owner", newOwner)
+	if oldOwner != "" && !b.config.Options.DisableReconnect {
+		b.forgetAll()
+	}
+}
+
+// actionInvoked translates an ActionInvoked signal back to the action ID
+func (b *dbusBackend) actionInvoked(signal *dbus.Signal) {
+	if len(signal.Body) < 2 {
+		return
+	}
+	id, ok := signal.Body[0].(uint32)
+	if !ok {
+		return
+	}
+	key, ok := signal.Body[1].(string)
+	if !ok {
+		return
+	}
+
+	b.mu.Lock()
+	actionID, ours := b.actionKeys[id][key]
+	b.mu.Unlock()
+
+	if ours && b.config.OnAction != nil {
+		b.config.OnAction(id, actionID)
+	}
+}
+
+// notificationClosed forwards a NotificationClosed signal
+func (b *dbusBackend) notificationClosed(signal *dbus.Signal) {
+	if len(signal.Body) < 2 {
+		return
+	}
+	id, ok := signal.Body[0].(uint32)
+	if !ok {
+		return
+	}
+	reason, _ := signal.Body[1].(uint32)
+
+	b.mu.Lock()
+	delete(b.actionKeys, id)
+	b.mu.Unlock()
+
+	if b.config.OnClosed != nil {
+		b.config.OnClosed(id, CloseReason(reason))
+	}
+}
@@ -0,0 +1,226 @@
+package notifications_test
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-music-players/notifications"
+	"github.com/godbus/dbus/v5"
+)
+
+// received is a notification the fake daemon was sent
+type received struct {
+	appName    string
+	replacesID uint32
+	summary    string
+	body       string
+	actions    []string
+	hints      map[string]dbus.Variant
+}
+
+// fakeDaemon implements org.freedesktop.Notifications on a private bus
+type fakeDaemon struct {
+	mu     sync.Mutex
+	notes  []received
+	nextID uint32
+}
+
+// startBus starts a private session bus and points the session bus
+// address at it, skipping where dbus-daemon isn't installed
+func startBus(tb testing.TB) string {
+	tb.Helper()
+	return startPrivateBus(tb, "DBUS_SESSION_BUS_ADDRESS")
+}
+
+// startPrivateBus starts a private bus and points the bus address in
+// variable at it, skipping where dbus-daemon isn't installed
+func startPrivateBus(tb testing.TB, variable string) string {
+	tb.Helper()
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		tb.Skip("dbus-daemon is not installed")
+	}
+	cmd := exec.Command("dbus-daemon", "--session", "--nofork", "--print-address")
+	address := startProcess(tb, cmd)
+	tb.Setenv(variable, address)
+	return address
+}
+
+// startProcess starts cmd, returning the first line it prints. It is
+// killed when the test ends.
+func startProcess(tb testing.TB, cmd *exec.Cmd) string {
+	tb.Helper()
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		tb.Fatalf("%s printed nothing: %v", cmd.Path, err)
+	}
+	return strings.TrimSpace(line)
+}
+
+// serveDaemon runs a fake notification daemon on the bus at address
+func serveDaemon(tb testing.TB, address string) *fakeDaemon {
+	tb.Helper()
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { conn.Close() })
+	daemon := &fakeDaemon{}
+	if err := conn.Export(daemon, "/org/freedesktop/Notifications", "org.freedesktop.Notifications"); err != nil {
+		tb.Fatal(err)
+	}
+	reply, err := conn.RequestName("org.freedesktop.Notifications", dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		tb.Fatalf("failed to own the notifications name: %v", err)
+	}
+	return daemon
+}
+
+// startDaemon runs a fake notification daemon in this process, on a
+// private session bus
+func startDaemon(tb testing.TB) *fakeDaemon {
+	tb.Helper()
+	return serveDaemon(tb, startBus(tb))
+}
+
+// startDaemonProcess runs a fake notification daemon in another process,
+// on a private session bus, so benchmarks don't count the daemon's work
+func startDaemonProcess(tb testing.TB) {
+	tb.Helper()
+	address := startBus(tb)
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFakeDaemonProcess$")
+	cmd.Env = append(os.Environ(), "FAKE_NOTIFICATION_DAEMON="+address)
+	if ready := startProcess(tb, cmd); ready != "ready" {
+		tb.Fatalf("fake daemon process printed %q", ready)
+	}
+}
+
+// TestFakeDaemonProcess is the fake daemon for startDaemonProcess, not a
+// real test
+func TestFakeDaemonProcess(t *testing.T) {
+	address := os.Getenv("FAKE_NOTIFICATION_DAEMON")
+	if address == "" {
+		t.Skip("only run by startDaemonProcess")
+	}
+	serveDaemon(t, address)
+	fmt.Println("ready")
+	select {} // Until killed
+}
+
+// GetCapabilities implements org.freedesktop.Notifications
+func (d *fakeDaemon) GetCapabilities() ([]string, *dbus.Error) {
+	return []string{"actions", "body", "body-markup", "icon-static"}, nil
+}
+
+// GetServerInformation implements org.freedesktop.Notifications
+func (d *fakeDaemon) GetServerInformation() (name, vendor, version, specVersion string, err *dbus.Error) {
+	return "fake", "notificationstest", "1.0", "1.2", nil
+}
+
+// Notify implements org.freedesktop.Notifications
+func (d *fakeDaemon) Notify(appName string, replacesID uint32, icon, summary, body string, actions []string, hints map[string]dbus.Variant, timeout int32) (uint32, *dbus.Error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	id := replacesID
+	if id == 0 {
+		d.nextID++
+		id = d.nextID
+	}
+	d.notes = append(d.notes, received{appName, replacesID, summary, body, actions, hints})
+	return id, nil
+}
+
+// CloseNotification implements org.freedesktop.Notifications
+func (d *fakeDaemon) CloseNotification(id uint32) *dbus.Error {
+	return nil
+}
+
+// received returns the notifications sent so far
+func (d *fakeDaemon) received() []received {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]received(nil), d.notes...)
+}
+
+func TestDBusRoundTrip(t *testing.T) {
+	daemon := startDaemon(t)
+	notifier, err := notifications.NewNotifier(notifications.WithAppName("Player"), notifications.WithBackend("dbus"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer notifier.Close()
+
+	track := &notifications.TrackInfo{Title: "Song", Artist: "Simon & Garfunkel", Album: "Bookends", ImageURL: writeCover(t, 100, 80)}
+	for _, title := range []string{"Song", "Next Song"} {
+		track.Title = title
+		if err := notifier.Notify(track, notifications.StatePlaying); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	notes := daemon.received()
+	if len(notes) != 2 {
+		t.Fatalf("daemon got %d notifications, want 2", len(notes))
+	}
+	first, second := notes[0], notes[1]
+	if first.appName != "Player" || first.summary != "Song" || first.body != "<b>Simon &amp; Garfunkel</b>\n<i>Bookends</i>" {
+		t.Errorf("daemon got %q: %q / %q", first.appName, first.summary, first.body)
+	}
+	if _, ok := first.hints["image-data"]; !ok {
+		t.Errorf("hints %v have no image-data", first.hints)
+	}
+	if _, ok := first.hints["image-path"]; !ok {
+		t.Errorf("hints %v have no image-path", first.hints)
+	}
+	if second.replacesID != 1 {
+		t.Errorf("second notification replaces %d, want 1", second.replacesID)
+	}
+}
+
+// BenchmarkDBusRoundTrip re-sends one track with a 1000x800 cover to a
+// fake daemon in another process, passing the art as pixels or as a path
+func BenchmarkDBusRoundTrip(b *testing.B) {
+	startDaemonProcess(b)
+	cover := writeCover(b, 1000, 800)
+	for _, mode := range []struct {
+		name string
+		mode notifications.ImageMode
+	}{
+		{"image-data", notifications.ImageData},
+		{"image-path", notifications.ImagePath},
+	} {
+		b.Run(mode.name, func(b *testing.B) {
+			notifier, err := notifications.NewNotifier(notifications.WithBackend("dbus"), notifications.WithOptions(func(o *notifications.Options) {
+				o.ImageMode = mode.mode
+			}))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer notifier.Close()
+			track := &notifications.TrackInfo{Title: "Song", Artist: "Band", Album: "Album", ImageURL: cover}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := notifier.NotifyNow(track, notifications.StatePlaying); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
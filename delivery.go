@@ -0,0 +1,113 @@
+package notifications
+
+import "time"
+
+// DeliveryStatus is the outcome of a notification attempt
+type DeliveryStatus string
+
+const (
+	DeliveryDelivered  DeliveryStatus = "Delivered"  // Accepted by the backend
+	DeliverySuppressed DeliveryStatus = "Suppressed" // Deliberately not shown
+	DeliveryFailed     DeliveryStatus = "Failed"     // The backend returned an error
+	DeliveryQueued     DeliveryStatus = "Queued"     // Held for a remote backend until it can be reached
+)
+
+// DeliveryReport describes what happened to a notification attempt, so host
+// apps can show whether notifications are working
+type DeliveryReport struct {
+	Backend string            // Backend that handled the attempt (e.g. "dbus")
+	Status  DeliveryStatus    // Outcome of the attempt
+	Reason  SuppressionReason // Why it was suppressed (Suppressed only)
+	ID      uint32            // Notification ID assigned by the backend (Delivered only)
+	Latency time.Duration     // Time spent delivering (Delivered, Failed and Queued only)
+	Err     error             // Delivery error (Failed, and Queued after a failed attempt)
+	Time    time.Time         // When the attempt finished
+}
+
+// Stats counts notification outcomes since the notifier was created, or
+// with Options.Store since counting first started
+type Stats struct {
+	Delivered  int                       `json:"delivered"`  // Notifications accepted by the backend
+	Failed     int                       `json:"failed"`     // Notifications the backend rejected
+	Queued     int                       `json:"queued"`     // Notifications held while offline
+	Suppressed map[SuppressionReason]int `json:"suppressed"` // Skipped notifications by reason
+	Since      time.Time                 `json:"since"`      // When counting started
+}
+
+// statsDays is how many days of StatsByDay are kept
+const statsDays = 30
+
+// today returns the counts for the day of t, starting a new day (and
+// dropping the oldest beyond statsDays) when it changed
+func today(days []Stats, t time.Time) ([]Stats, *Stats) {
+	year, month, day := t.Date()
+	start := time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	if len(days) == 0 || !days[len(days)-1].Since.Equal(start) {
+		days = append(days, Stats{Since: start})
+		if len(days) > statsDays {
+			days = days[len(days)-statsDays:]
+		}
+	}
+	return days, &days[len(days)-1]
+}
+
+// record counts a delivery report
+func (s *Stats) record(r DeliveryReport) {
+	switch r.Status {
+	case DeliveryDelivered:
+		s.Delivered++
+	case DeliveryFailed:
+		s.Failed++
+	case DeliveryQueued:
+		s.Queued++
+	case DeliverySuppressed:
+		if s.Suppressed == nil {
+			s.Suppressed = make(map[SuppressionReason]int)
+		}
+		s.Suppressed[r.Reason]++
+	}
+}
+
+// Metrics receives counts and timings of notification attempts, for
+// exporting to a monitoring system such as Prometheus. Methods are called
+// synchronously from the notifier, and may be called concurrently when
+// several notifiers share one Metrics.
+type Metrics interface {
+	// Delivered counts a notification accepted by the backend
+	Delivered(backend string)
+
+	// Suppressed counts a notification deliberately not shown, e.g.
+	// SuppressSameTrack for deduplicated ones
+	Suppressed(backend string, reason SuppressionReason)
+
+	// Failed counts a notification the backend returned an error for
+	Failed(backend string)
+
+	// CallLatency observes how long the backend took to deliver or fail,
+	// including retries; for the D-Bus backend, the Notify calls
+	CallLatency(backend string, latency time.Duration)
+}
+
+// observe passes a delivery report on to metrics
+func observe(m Metrics, r DeliveryReport) {
+	switch r.Status {
+	case DeliveryDelivered:
+		m.Delivered(r.Backend)
+		m.CallLatency(r.Backend, r.Latency)
+	case DeliveryFailed:
+		m.Failed(r.Backend)
+		m.CallLatency(r.Backend, r.Latency)
+	case DeliverySuppressed:
+		m.Suppressed(r.Backend, r.Reason)
+	}
+}
+
+// clone returns a copy that doesn't share the Suppressed map
+func (s Stats) clone() Stats {
+	suppressed := make(map[SuppressionReason]int, len(s.Suppressed))
+	for reason, count := range s.Suppressed {
+		suppressed[reason] = count
+	}
+	s.Suppressed = suppressed
+	return s
+}
@@ -0,0 +1,90 @@
+//go:build linux
+
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// D-Bus error names that indicate a specific failure mode
+const (
+	dbusErrAccessDenied   = "org.freedesktop.DBus.Error.AccessDenied"
+	dbusErrServiceUnknown = "org.freedesktop.DBus.Error.ServiceUnknown"
+	dbusErrNameHasNoOwner = "org.freedesktop.DBus.Error.NameHasNoOwner"
+	dbusErrNoReply        = "org.freedesktop.DBus.Error.NoReply"
+	dbusErrTimeout        = "org.freedesktop.DBus.Error.Timeout"
+	dbusErrTimedOut       = "org.freedesktop.DBus.Error.TimedOut"
+)
+
+// diagnoseConnect classifies a failure to connect to the session bus
+func diagnoseConnect(err error) error {
+	switch {
+	case os.Getenv("DBUS_SESSION_BUS_ADDRESS") == "" && os.Getenv("XDG_RUNTIME_DIR") == "":
+		return &ConnectError{
+			Reason: ErrNoSessionBus,
+			Hint:   "DBUS_SESSION_BUS_ADDRESS and XDG_RUNTIME_DIR are unset; run inside a graphical session or export the session bus address",
+			Err:    err,
+		}
+	case errors.Is(err, syscall.ENOENT):
+		return &ConnectError{
+			Reason: ErrNoSessionBus,
+			Hint:   "the session bus socket does not exist; is dbus-daemon or dbus-broker running for this user?",
+			Err:    err,
+		}
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return &ConnectError{
+			Reason: ErrBusRefused,
+			Hint:   "the session bus socket is stale; restart the session bus or log in again",
+			Err:    err,
+		}
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		return &ConnectError{
+			Reason: ErrPermissionDenied,
+			Hint:   "the session bus socket is not accessible; check file ownership and SELinux/AppArmor policy for this process",
+			Err:    err,
+		}
+	default:
+		return &ConnectError{
+			Reason: ErrNoSessionBus,
+			Hint:   "could not determine or reach the session bus address",
+			Err:    err,
+		}
+	}
+}
+
+// diagnoseCall classifies a failed call to the notification daemon.
+// Errors that don't match a known failure mode are returned unchanged.
+func diagnoseCall(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	var dbusErr dbus.Error
+	if !errors.As(err, &dbusErr) {
+		return err
+	}
+
+	switch dbusErr.Name {
+	case dbusErrNoReply, dbusErrTimeout, dbusErrTimedOut:
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	case dbusErrAccessDenied:
+		return &ConnectError{
+			Reason: ErrPermissionDenied,
+			Hint:   "the bus policy rejected the call; sandboxed apps need the org.freedesktop.Notifications talk permission",
+			Err:    err,
+		}
+	case dbusErrServiceUnknown, dbusErrNameHasNoOwner:
+		return &ConnectError{
+			Reason: ErrNoDaemon,
+			Hint:   "install or start a notification daemon such as dunst, mako or your desktop's notification service",
+			Err:    err,
+		}
+	default:
+		return err
+	}
+}
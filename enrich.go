@@ -0,0 +1,72 @@
+package notifications
+
+import (
+	"context"
+	"time"
+)
+
+// defaultEnrichBudget bounds how long enrichment may delay a notification
+const defaultEnrichBudget = 500 * time.Millisecond
+
+// Enricher adds or corrects metadata on a track before it is rendered.
+// It should respect ctx cancellation; late results are discarded.
+type Enricher func(ctx context.Context, track *TrackInfo) error
+
+// EnrichStage is a named Enricher with its own timeout
+type EnrichStage struct {
+	Name    string        // Stage name, for diagnostics
+	Enrich  Enricher      // Enrichment function
+	Timeout time.Duration // Per-stage timeout (0 = remaining budget)
+}
+
+// enrich runs stages in order on a copy of track, never taking longer than
+// budget in total. Stages that fail or time out leave the track unchanged.
+func enrich(stages []EnrichStage, budget time.Duration, track *TrackInfo) *TrackInfo {
+	if len(stages) == 0 {
+		return track
+	}
+	if budget <= 0 {
+		budget = defaultEnrichBudget
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
+	defer cancel()
+
+	current := *track
+	for _, stage := range stages {
+		if ctx.Err() != nil {
+			break
+		}
+		if result, ok := runStage(ctx, stage, current); ok {
+			current = result
+		}
+	}
+
+	return &current
+}
+
+// runStage runs one stage on its own copy of the track, so an enricher that
+// ignores its context can't modify the track after its deadline
+func runStage(ctx context.Context, stage EnrichStage, track TrackInfo) (TrackInfo, bool) {
+	if stage.Enrich == nil {
+		return track, false
+	}
+
+	if stage.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, stage.Timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- stage.Enrich(ctx, &track)
+	}()
+
+	select {
+	case err := <-done:
+		return track, err == nil
+	case <-ctx.Done():
+		return TrackInfo{}, false
+	}
+}
@@ -0,0 +1,69 @@
+package notifications
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Connection failure modes. Errors returned by NewNotifier can be matched
+// against these with errors.Is to tell the failure modes apart.
+var (
+	// ErrNoSessionBus means no session bus address could be found
+	ErrNoSessionBus = errors.New("no D-Bus session bus")
+
+	// ErrBusRefused means the session bus socket exists but nothing is listening
+	ErrBusRefused = errors.New("D-Bus session bus refused connection")
+
+	// ErrPermissionDenied means a file permission or MAC policy
+	// (SELinux, AppArmor, Flatpak sandbox) denied access
+	ErrPermissionDenied = errors.New("access to D-Bus denied")
+
+	// ErrNoDaemon means the bus is reachable but no notification daemon is running
+	ErrNoDaemon = errors.New("no notification daemon")
+)
+
+// Delivery failure modes. Errors returned while showing, updating or
+// closing notifications can be matched against these (and ErrNoDaemon)
+// with errors.Is.
+var (
+	// ErrTimeout means the notification service didn't answer in time,
+	// e.g. because it was busy; trying again later may work
+	ErrTimeout = errors.New("notification service timed out")
+
+	// ErrCapabilityMissing means the backend can't do what was asked, such
+	// as replacing notifications for live updates
+	ErrCapabilityMissing = errors.New("notification backend lacks capability")
+
+	// ErrClosed means the Notifier was closed
+	ErrClosed = errors.New("notifier closed")
+
+	// ErrOffline means a remote backend couldn't reach its destination,
+	// e.g. because the network is down
+	ErrOffline = errors.New("notification destination unreachable")
+
+	// ErrRateLimited means a remote destination's rate limit held the
+	// notification back too long, or the destination refused it for
+	// coming too fast (see RateLimitError)
+	ErrRateLimited = errors.New("notification rate limited")
+)
+
+// ConnectError describes why the notifier could not reach the notification
+// daemon, with a hint on how to fix it
+type ConnectError struct {
+	Reason error  // One of ErrNoSessionBus, ErrBusRefused, ErrPermissionDenied, ErrNoDaemon
+	Hint   string // Suggested remediation
+	Err    error  // Underlying error
+}
+
+func (e *ConnectError) Error() string {
+	msg := fmt.Sprintf("%v: %v", e.Reason, e.Err)
+	if e.Hint != "" {
+		msg += " (" + e.Hint + ")"
+	}
+	return msg
+}
+
+// Unwrap returns both the failure reason and the underlying error
+func (e *ConnectError) Unwrap() []error {
+	return []error{e.Reason, e.Err}
+}
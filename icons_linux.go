@@ -0,0 +1,102 @@
+//go:build linux
+
+package notifications
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// iconExtensions are the image formats icon themes may use
+var iconExtensions = []string{".png", ".svg", ".xpm"}
+
+// resolveIcon returns the first of icon and its fallbacks that can actually
+// be found, so daemons don't render a broken-image glyph. Without fallbacks
+// the icon is used as is.
+func (n *Notifier) resolveIcon(icon string) string {
+	if len(n.opts().IconFallbacks) == 0 {
+		return n.rasterizeIcon(icon)
+	}
+
+	for _, candidate := range append([]string{icon}, n.opts().IconFallbacks...) {
+		if candidate != "" && n.iconExists(candidate) {
+			return n.rasterizeIcon(candidate)
+		}
+	}
+	return n.rasterizeIcon(icon)
+}
+
+// iconExists reports whether an icon name or path can be found, caching
+// the answer since theme lookups walk the filesystem
+func (n *Notifier) iconExists(icon string) bool {
+	if found, ok := n.iconCache[icon]; ok {
+		return found
+	}
+
+	var found bool
+	if path, ok := strings.CutPrefix(icon, "file://"); ok {
+		found = fileExists(path)
+	} else if filepath.IsAbs(icon) {
+		found = fileExists(icon)
+	} else {
+		found = themeIconExists(icon)
+	}
+
+	if n.iconCache == nil {
+		n.iconCache = make(map[string]bool)
+	}
+	n.iconCache[icon] = found
+	return found
+}
+
+// themeIconExists looks an icon name up in the installed icon themes
+// (hicolor, the current theme and any other) and in the pixmaps directory,
+// as described by the freedesktop Icon Theme Specification
+func themeIconExists(name string) bool {
+	for _, base := range iconBaseDirs() {
+		// Themes are laid out as <theme>/<size>/<context>/<name>.<ext>
+		// (or <theme>/<context>/<size>/...), so two wildcard levels cover both
+		for _, ext := range iconExtensions {
+			matches, _ := filepath.Glob(filepath.Join(base, "*", "*", "*", name+ext))
+			if len(matches) > 0 {
+				return true
+			}
+		}
+	}
+
+	for _, ext := range iconExtensions {
+		if fileExists(filepath.Join("/usr/share/pixmaps", name+ext)) {
+			return true
+		}
+	}
+	return false
+}
+
+// iconBaseDirs returns the icon theme base directories in lookup order
+func iconBaseDirs() []string {
+	var dirs []string
+	if home, err := os.UserHomeDir(); err == nil {
+		dirs = append(dirs, filepath.Join(home, ".icons"))
+	}
+
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			dataHome = filepath.Join(home, ".local", "share")
+		}
+	}
+	if dataHome != "" {
+		dirs = append(dirs, filepath.Join(dataHome, "icons"))
+	}
+
+	dataDirs := os.Getenv("XDG_DATA_DIRS")
+	if dataDirs == "" {
+		dataDirs = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range filepath.SplitList(dataDirs) {
+		dirs = append(dirs, filepath.Join(dir, "icons"))
+	}
+
+	return dirs
+}
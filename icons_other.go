@@ -0,0 +1,8 @@
+//go:build !linux
+
+package notifications
+
+// resolveIcon returns the icon as is; icon themes are a freedesktop concept
+func (n *Notifier) resolveIcon(icon string) string {
+	return icon
+}
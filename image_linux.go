@@ -0,0 +1,34 @@
+//go:build linux
+
+package notifications
+
+import (
+	"image"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// imageData is the (iiibiiay) structure of the image-data hint
+type imageData struct {
+	Width         int32
+	Height        int32
+	RowStride     int32
+	HasAlpha      bool
+	BitsPerSample int32
+	Channels      int32
+	Data          []byte
+}
+
+// imageDataHint encodes an image as raw pixels for the image-data hint
+func imageDataHint(img *image.NRGBA) dbus.Variant {
+	bounds := img.Bounds()
+	return dbus.MakeVariant(imageData{
+		Width:         int32(bounds.Dx()),
+		Height:        int32(bounds.Dy()),
+		RowStride:     int32(img.Stride),
+		HasAlpha:      true,
+		BitsPerSample: 8,
+		Channels:      4,
+		Data:          img.Pix,
+	})
+}
@@ -0,0 +1,120 @@
+//go:build linux
+
+package notifications
+
+import (
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+)
+
+const (
+	sessionManagerInterface = "org.gnome.SessionManager"
+	sessionManagerPath      = "/org/gnome/SessionManager"
+	inhibitorInterface      = "org.gnome.SessionManager.Inhibitor"
+
+	// inhibitIdle is the GNOME session manager flag set by presentation
+	// tools, but also by every video player
+	inhibitIdle uint32 = 8
+
+	// Mutter keeps an object per screen cast session, which is how
+	// xdg-desktop-portal-gnome serves the portal's ScreenCast (and
+	// RemoteDesktop) sessions
+	mutterScreenCastService  = "org.gnome.Mutter.ScreenCast"
+	mutterScreenCastSessions = "/org/gnome/Mutter/ScreenCast/Session"
+)
+
+// presentationReasons are words in the reasons presentation tools and
+// screen sharing give for inhibiting idle. Video players ("Playing video")
+// inhibit idle too, so the flag alone isn't enough.
+var presentationReasons = []string{"present", "slideshow", "slide show", "screen shar", "screencast", "sharing"}
+
+// dndCacheTTL is how long a Do Not Disturb reading is reused, since it
+// costs a round trip and, on GNOME, running gsettings
+const dndCacheTTL = 5 * time.Second
+
+// PresentationActive reports whether the desktop is currently presenting or
+// sharing the screen. Any D-Bus failure is treated as "not presenting" so a
+// missing service never blocks notifications.
+func (b *dbusBackend) PresentationActive() bool {
+	// KDE Plasma (and other daemons implementing the extension) set the
+	// Inhibited property in presentation mode and while the screen is
+	// being shared
+	if b.inhibited() {
+		return true
+	}
+
+	// GNOME: the portal is sharing the screen, or a presentation tool
+	// inhibits idle
+	return b.screenCasting() || b.presenting()
+}
+
+// screenCasting reports whether Mutter has a screen cast session open
+func (b *dbusBackend) screenCasting() bool {
+	node, err := introspect.Call(b.bus().Object(mutterScreenCastService, mutterScreenCastSessions))
+	return err == nil && len(node.Children) > 0
+}
+
+// presenting reports whether an application inhibits idle on GNOME for a
+// presentation or screen sharing
+func (b *dbusBackend) presenting() bool {
+	sm := b.bus().Object(sessionManagerInterface, dbus.ObjectPath(sessionManagerPath))
+	var inhibitors []dbus.ObjectPath
+	if err := sm.Call(sessionManagerInterface+".GetInhibitors", 0).Store(&inhibitors); err != nil {
+		return false
+	}
+
+	for _, path := range inhibitors {
+		inhibitor := b.bus().Object(sessionManagerInterface, path)
+		var flags uint32
+		if err := inhibitor.Call(inhibitorInterface+".GetFlags", 0).Store(&flags); err != nil || flags&inhibitIdle == 0 {
+			continue
+		}
+		var reason string
+		if err := inhibitor.Call(inhibitorInterface+".GetReason", 0).Store(&reason); err != nil {
+			continue
+		}
+		reason = strings.ToLower(reason)
+		for _, word := range presentationReasons {
+			if strings.Contains(reason, word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DoNotDisturb reports whether the user turned on Do Not Disturb. Failures
+// are treated as "off".
+func (b *dbusBackend) DoNotDisturb() bool {
+	b.dndMu.Lock()
+	defer b.dndMu.Unlock()
+
+	if time.Since(b.dndChecked) > dndCacheTTL {
+		// KDE Plasma's Do Not Disturb also sets Inhibited
+		b.dnd = b.inhibited() || gnomeDoNotDisturb()
+		b.dndChecked = time.Now()
+	}
+	return b.dnd
+}
+
+// inhibited reads the Inhibited property of the notification daemon
+func (b *dbusBackend) inhibited() bool {
+	obj := b.bus().Object(notificationsInterface, notificationsPath)
+	v, err := obj.GetProperty(notificationsInterface + ".Inhibited")
+	if err != nil {
+		return false
+	}
+	inhibited, ok := v.Value().(bool)
+	return ok && inhibited
+}
+
+// gnomeDoNotDisturb reads GNOME's Do Not Disturb switch, which turns off
+// notification banners
+func gnomeDoNotDisturb() bool {
+	out, err := exec.Command("gsettings", "get", "org.gnome.desktop.notifications", "show-banners").Output()
+	return err == nil && strings.TrimSpace(string(out)) == "false"
+}
@@ -1,15 +1,111 @@
 package notifications
 
-import "time"
+import (
+	"log/slog"
+	"strings"
+	"time"
+)
 
 // TrackInfo represents track metadata for notifications
 type TrackInfo struct {
-	Title    string        // Track title
-	Artist   string        // Artist name
-	Album    string        // Album name
-	Station  string        // Station name (for radio/streaming)
-	ImageURL string        // Album art or station logo URL
-	Duration time.Duration // Total track duration (0 if unknown)
+	Title       string        // Track title
+	Artist      string        // Artist name
+	Album       string        // Album name
+	TrackNumber int           // Position on the album (0 if unknown)
+	Station     string        // Station name (for radio/streaming)
+	ImageURL    string        // Album art or station logo (path, file:// or http(s):// URL)
+	Duration    time.Duration // Total track duration (0 if unknown)
+	Position    time.Duration // Current playback position (0 if unknown)
+	Source      string        // Source player identity (e.g. "spotify", "mpd")
+	Origin      Origin        // Where the track plays, in more detail (optional)
+
+	ReplayGain float64 // ReplayGain track gain in dB (0 if unknown)
+	Loudness   float64 // Integrated loudness in LUFS (0 if unknown)
+
+	Lyrics   []LyricLine // Synced lyrics, in order (nil if unknown)
+	Chapters []Chapter   // Podcast or audiobook chapters, in order (nil for none)
+}
+
+// Origin describes where a track is playing, for setups with several
+// players, instances or machines
+type Origin struct {
+	Player   string // Player name, e.g. "MPD"
+	Instance string // Which of several instances of the player, e.g. "kitchen"
+	Host     string // Machine the player runs on (empty for this one)
+}
+
+// String formats the origin for display, e.g. "MPD (kitchen) on server"
+func (o Origin) String() string {
+	s := o.Player
+	if o.Instance != "" {
+		s += " (" + o.Instance + ")"
+	}
+	if o.Host != "" {
+		s += " on " + o.Host
+	}
+	return s
+}
+
+// key identifies the origin as a source, e.g. "MPD/kitchen@server"
+func (o Origin) key() string {
+	key := o.Player
+	if o.Instance != "" {
+		key += "/" + o.Instance
+	}
+	if o.Host != "" {
+		key += "@" + o.Host
+	}
+	return key
+}
+
+// sourcePlayer returns the player part of a source derived from an
+// Origin or an MPRIS bus name ("vlc.instance1234"), so per-player
+// settings apply to all its instances and hosts
+func sourcePlayer(source string) string {
+	if i := strings.IndexAny(source, "/@."); i >= 0 {
+		return source[:i]
+	}
+	return source
+}
+
+// replayGainReference is the loudness ReplayGain 2.0 normalizes to, in LUFS
+const replayGainReference = -18.0
+
+// loudness returns the track's integrated loudness in LUFS, derived from
+// ReplayGain when no measured value is available
+func (t *TrackInfo) loudness() (float64, bool) {
+	if t.Loudness != 0 {
+		return t.Loudness, true
+	}
+	if t.ReplayGain != 0 {
+		return replayGainReference - t.ReplayGain, true
+	}
+	return 0, false
+}
+
+// progress returns how far into the track playback is, as a percentage
+func (t *TrackInfo) progress() (int, bool) {
+	if t.Duration <= 0 {
+		return 0, false
+	}
+	percent := int(100 * t.Position / t.Duration)
+	return min(max(percent, 0), 100), true
+}
+
+// sampleTrack returns a representative track for test notifications
+func sampleTrack() *TrackInfo {
+	return &TrackInfo{
+		Title:    "Bohemian Rhapsody",
+		Artist:   "Queen",
+		Album:    "A Night at the Opera",
+		Duration: 5*time.Minute + 55*time.Second,
+	}
+}
+
+// AppIdentity is the app name and icon a notification is shown under
+type AppIdentity struct {
+	AppName string // Application name shown in notifications
+	Icon    string // Icon name or path
 }
 
 // PlaybackState represents the current playback state
@@ -21,13 +117,456 @@ const (
 	StateStopped PlaybackState = "Stopped"
 )
 
+// SuppressionReason explains why Notify decided not to show a notification
+type SuppressionReason string
+
+const (
+	SuppressNoTrack       SuppressionReason = "NoTrack"       // Track was nil or had no title/artist
+	SuppressPaused        SuppressionReason = "Paused"        // Paused and NotifyOnPause is disabled
+	SuppressSnoozed       SuppressionReason = "Snoozed"       // Muted via Snooze
+	SuppressPresentation  SuppressionReason = "Presentation"  // Presentation mode or screen sharing active
+	SuppressSameTrack     SuppressionReason = "SameTrack"     // Track already notified
+	SuppressBudget        SuppressionReason = "Budget"        // Per-track notification budget used up
+	SuppressLowerPriority SuppressionReason = "LowerPriority" // A higher-priority source is playing
+	SuppressSampled       SuppressionReason = "Sampled"       // Skipped by the sampling policy
+	SuppressUnsubscribed  SuppressionReason = "Unsubscribed"  // The backend doesn't want this event
+	SuppressDoNotDisturb  SuppressionReason = "DoNotDisturb"  // The desktop is in Do Not Disturb mode
+	SuppressStale         SuppressionReason = "Stale"         // Delayed past StaleAfter before it could be shown
+	SuppressRule          SuppressionReason = "Rule"          // A rule in Options.Rules suppresses the track
+	SuppressBlockedArtist SuppressionReason = "BlockedArtist" // The artist was muted with BlockArtist
+	SuppressDeferred      SuppressionReason = "Deferred"      // Another instance shows notifications, see Options.Coordinate
+)
+
+// CloseReason explains why a notification was closed
+type CloseReason uint32
+
+const (
+	CloseExpired   CloseReason = 1 // The notification timed out
+	CloseDismissed CloseReason = 2 // The user dismissed it
+	CloseClosed    CloseReason = 3 // Closed by the application (DismissAll, Reset)
+	CloseUndefined CloseReason = 4 // Any other reason
+)
+
+// DedupScope controls whether deduplication is shared between sources
+type DedupScope int
+
+const (
+	// DedupGlobal deduplicates across all sources, so the same track reported
+	// by two watchers notifies once
+	DedupGlobal DedupScope = iota
+
+	// DedupPerSource deduplicates each source separately, so distinct players
+	// never mask each other
+	DedupPerSource
+)
+
+// ImageMode chooses how album art is passed to the notification daemon
+type ImageMode int
+
+const (
+	// ImageAuto picks per daemon, sending both where nothing is known
+	ImageAuto ImageMode = iota
+
+	// ImageData embeds raw pixels (image-data), which works even when the
+	// daemon can't read the file, e.g. in a sandbox
+	ImageData
+
+	// ImagePath passes the path of the (cached) file (image-path)
+	ImagePath
+
+	// ImageBoth sends both and lets the daemon choose
+	ImageBoth
+)
+
+// Event is what a notification is about, so backends can subscribe to the
+// ones they care about
+type Event int
+
+const (
+	// EventStarted is a track starting (or otherwise changing)
+	EventStarted Event = iota
+
+	// EventListened is a track played past Options.ListenedAt, for
+	// scrobble-like destinations
+	EventListened
+
+	// EventMessage is a notification that isn't about a track, such as a
+	// snooze confirmation
+	EventMessage
+)
+
+// Action is a button on a notification. Each backend maps it to its native
+// mechanism, so controls are defined once for every platform.
+type Action struct {
+	ID      string                 // Identifier, unique within a notification ("default" is a click on the notification itself)
+	Label   string                 // Button text
+	Icon    string                 // Icon name, used where the backend can show icon buttons
+	Handler func(track *TrackInfo) // Called when the action is invoked, from an internal goroutine
+}
+
 // Options configures notification behavior
 type Options struct {
 	AppName         string // Application name shown in notifications
 	Icon            string // Icon name (defaults to "media-playback-start")
 	Timeout         int32  // Notification timeout in milliseconds (default: 5000)
 	NotifyOnPause   bool   // Show notification when paused (default: false)
+	ClearOnStop     bool   // Close the track notification on StateStopped (default: false)
 	ReplaceExisting bool   // Replace previous notification instead of stacking (default: true)
+
+	// DesktopEntry is the player's .desktop file name without the suffix,
+	// e.g. "org.gnome.Lollypop". Daemons use it to group notifications and
+	// to find the app's icon and settings. (default: "")
+	DesktopEntry string
+
+	// Transient keeps track notifications out of the notification history
+	// (GNOME's message tray, KDE's history), so they flash and disappear
+	// instead of piling up. Messages and errors are kept. (default: false)
+	Transient bool
+
+	// SoundName and SoundFile play a sound with track notifications: a
+	// sound theme name such as "message-new-instant", or the path of a
+	// sound file. SuppressSound instead silences the daemon's own sound for
+	// them. Messages and errors keep the daemon's default. (default: none)
+	SoundName     string
+	SoundFile     string
+	SuppressSound bool
+
+	// Urgency of track notifications, e.g. UrgencyLow so routine track
+	// changes don't linger. A renderer returning another urgency takes
+	// precedence; NotifyError is always critical. (default: UrgencyNormal)
+	Urgency Urgency
+
+	// SuppressDuringPresentation skips notifications while the desktop reports
+	// that notifications are inhibited (KDE presentation mode, screen sharing),
+	// the screen is shared through xdg-desktop-portal on GNOME, or an
+	// application inhibits idle for a presentation. Prevents leaking what
+	// you're listening to in meetings. (default: false)
+	SuppressDuringPresentation bool
+
+	// RespectDoNotDisturb skips notifications while the desktop is in Do
+	// Not Disturb mode (GNOME, KDE Plasma) (default: true)
+	RespectDoNotDisturb bool
+
+	// DoNotDisturbAllowErrors still shows NotifyError notifications in Do
+	// Not Disturb mode (default: false)
+	DoNotDisturbAllowErrors bool
+
+	// SourceApps maps TrackInfo.Source to the app name and icon used for that
+	// player, so notifications driven for several players don't all appear
+	// under one global identity. Empty fields fall back to AppName/Icon.
+	SourceApps map[string]AppIdentity
+
+	// OnSuppressed is called whenever Notify decides not to show anything,
+	// with the reason why. Useful for debugging missing popups. It runs
+	// inside Notify, so it must not call Notify or the other showing
+	// methods. May be nil.
+	OnSuppressed func(track *TrackInfo, reason SuppressionReason)
+
+	// Rules change how matching tracks are shown, or suppress them, in
+	// order (default: none)
+	Rules []Rule
+
+	// Renderer produces the summary and body for each notification
+	// (default: DefaultRenderer)
+	Renderer Renderer
+
+	// SummaryTemplate and BodyTemplate customize the notification text
+	// with text/template; see NewTemplateRenderer. Either may be empty to
+	// keep the default layout. Ignored when Renderer is set. (default: "")
+	SummaryTemplate string
+	BodyTemplate    string
+
+	// Templates overrides the templates per locale, e.g. "de" or "pt_BR",
+	// for the locale in Locale. A territory falls back to its language.
+	// (default: none)
+	Templates map[string]TemplateSet
+
+	// Locale selects from Templates (default: from LC_ALL, LC_MESSAGES or
+	// LANG)
+	Locale string
+
+	// StationRenderer renders notifications for a change of radio station,
+	// which is usually a bigger event than the stream's song rotation.
+	// Song changes within a station still use Renderer. (default: Renderer)
+	StationRenderer Renderer
+
+	// NotifyOnChapter notifies when a new chapter of a podcast episode or
+	// audiobook starts, for tracks with Chapters. (default: false)
+	NotifyOnChapter bool
+
+	// ChapterRenderer renders chapter notifications (default: chapter
+	// title as summary, episode and show as body, with the chapter's art)
+	ChapterRenderer Renderer
+
+	// ChapterTemplate renders chapter notifications from templates, with
+	// TemplateData.Chapter set, unless ChapterRenderer is set
+	// (default: none)
+	ChapterTemplate TemplateSet
+
+	// ShowLoudness adds the track's loudness and ReplayGain to the body
+	// (default: false)
+	ShowLoudness bool
+
+	// ShowProgress adds a gauge of how far into the track playback is,
+	// from Position and Duration, on daemons that draw the value hint
+	// (dunst, mako, xfce4-notifyd). Others ignore it. (default: false)
+	ShowProgress bool
+
+	// SquashAlbums turns consecutive tracks of one album into a single
+	// updating notification listing them ("3. Song A ✓", "4. Song B ▶"),
+	// starting over when the album changes. (default: false)
+	SquashAlbums bool
+
+	// ShowOrigin adds where the track plays to the body, e.g. "via MPD
+	// (kitchen) on server", for tracks with an Origin (default: false)
+	ShowOrigin bool
+
+	// LiveUpdateInterval is how often StartLiveUpdates, StartCountdown and
+	// StartLyrics refresh the notification (default: 1s, and for lyrics 250ms to 1s
+	// by daemon)
+	LiveUpdateInterval time.Duration
+
+	// LoudnessWarning shows a warning when a track is at least this many LU
+	// louder than the previous one. 0 disables the warning. (default: 0)
+	LoudnessWarning float64
+
+	// Enrichers run in order before each notification is rendered, e.g. to
+	// fetch a genre or fix casing. Each stage gets its own timeout within
+	// EnrichBudget; slow or failing stages are skipped.
+	Enrichers []EnrichStage
+
+	// EnrichBudget is the total time enrichment may delay a notification
+	// (default: 500ms)
+	EnrichBudget time.Duration
+
+	// MaxPerTrack limits how many notifications (including in-place edits
+	// and live updates) one track may produce, so features combined on
+	// daemons without proper replacement can't flood the screen. 0 means
+	// unlimited. (default: 0)
+	MaxPerTrack int
+
+	// OnDelivery is called with the outcome of every notification attempt.
+	// Like OnSuppressed, it must not call the showing methods. May be nil;
+	// LastDelivery returns the most recent report either way.
+	OnDelivery func(report DeliveryReport)
+
+	// Tracer traces showing notifications: rendering, loading art and the
+	// backend call, in the context passed with NotifyContext (default: nil)
+	Tracer Tracer
+
+	// Metrics receives counts and latencies of every notification
+	// attempt, see Metrics (default: nil)
+	Metrics Metrics
+
+	// Actions are added to every track notification (if the daemon supports
+	// actions), after any actions returned by the Renderer
+	Actions []Action
+
+	// OnRate adds rating buttons to track notifications: five stars where
+	// the daemon can show them, or a "Rate…" button calling OnRate with 0
+	// stars where it can't. Called from an internal goroutine. May be nil.
+	OnRate func(track *TrackInfo, stars int)
+
+	// SnoozeFor adds a "Mute for 30 min" button to track notifications,
+	// calling Snooze for this long (default: 0, no button)
+	SnoozeFor time.Duration
+
+	// BlockArtistAction adds a "Don't notify for this artist" button to
+	// track notifications, calling BlockArtist (default: false)
+	BlockArtistAction bool
+
+	// CopyTrackAction adds a "Copy" button to track notifications, calling
+	// CopyTrack (default: false)
+	CopyTrackAction bool
+
+	// CopyTemplate is the text/template CopyTrack copies, executed with a
+	// TemplateData (default: "{{if .Artist}}{{.Artist}} – {{end}}{{.Title}}")
+	CopyTemplate string
+
+	// OnRaise is called when the user clicks a track notification's body,
+	// to bring the player to the front. A Watcher raises MPRIS players
+	// itself when OnRaise is nil. Called from an internal goroutine.
+	OnRaise func(track *TrackInfo)
+
+	// IconFallbacks are tried in order when the icon can't be found in the
+	// installed icon themes, so daemons never show a broken-image glyph.
+	// Leave empty to pass icons through unchecked.
+	// (default: "media-playback-start", "audio-x-generic")
+	IconFallbacks []string
+
+	// RasterizeSVGIcons converts SVG icon paths to PNGs of this size (in
+	// pixels) for daemons that can't render SVG. Requires rsvg-convert;
+	// 0 disables. (default: 0)
+	RasterizeSVGIcons int
+
+	// ArtTimeout bounds downloading remote album art. Downloaded art is
+	// cached on disk by URL, so each image is only fetched once.
+	// (default: 3s)
+	ArtTimeout time.Duration
+
+	// ArtFetcher downloads remote album art instead of the built-in HTTP
+	// client, for art only the player can reach (an authenticated CDN, a
+	// local media server). It gets every ImageURL that isn't a local file,
+	// whatever its scheme, and returns the image file's contents. Results
+	// are cached like downloads, and it is given up on after ArtTimeout.
+	// (default: nil)
+	ArtFetcher func(url string) ([]byte, error)
+
+	// PowerSaving sheds work while power-profiles-daemon is in a profile,
+	// by profile name (see ProfilePowerSaver). Profiles not listed shed
+	// nothing; an empty map doesn't watch the profile at all. Linux only.
+	// (default: power-saver skips art downloads and live updates)
+	PowerSaving map[string]PowerSaving
+
+	// StaleAfter drops a track notification that couldn't be shown within
+	// this long of Notify being called, e.g. because the machine suspended
+	// while art was downloading, rather than popping outdated "now
+	// playing" info. Suspended time counts. (default: 30s, 0 = never)
+	StaleAfter time.Duration
+
+	// MaxImageBytes caps the raw album art sent with a notification. Larger
+	// art is downscaled to fit, or left out (keeping only its path) if it
+	// can't be, instead of failing with an opaque bus error.
+	// (default: 1 MiB, 0 = the bus maximum)
+	MaxImageBytes int
+
+	// ImageMode chooses how album art is passed to D-Bus daemons. Auto
+	// picks per daemon from known quirks. (default: ImageAuto)
+	ImageMode ImageMode
+
+	// DedupScope selects global or per-source deduplication when several
+	// sources feed one notifier (default: DedupGlobal)
+	DedupScope DedupScope
+
+	// SourcePriority orders sources from most to least important. With
+	// DedupGlobal, a source is suppressed while a higher-priority source is
+	// playing. Unlisted sources rank lowest.
+	SourcePriority []string
+
+	// ResumeAfter re-notifies when playback resumes after being paused for
+	// at least this long, adding "Resumed after 12 min" to the body.
+	// 0 disables. (default: 0)
+	ResumeAfter time.Duration
+
+	// OnClosed is called, from an internal goroutine, when a notification
+	// shown by this notifier is closed. May be nil.
+	OnClosed func(id uint32, reason CloseReason)
+
+	// NotifyOnRestart notifies "Restarted: …" when the same track starts
+	// over from the beginning, detected from Position jumping back to ~0.
+	// (default: false)
+	NotifyOnRestart bool
+
+	// Backend is the name of the registered backend to deliver through,
+	// see Register (default: "dbus" on Linux, "toast" on Windows, "macos"
+	// on macOS)
+	Backend string
+
+	// FallbackBackends are tried in order when Backend can't be opened.
+	// Leaving both unset falls back to "portal" and then "notify-send" on
+	// Linux; set an empty slice to disable that. (default: nil)
+	FallbackBackends []string
+
+	// Coordinate makes instances in the session that use this package,
+	// such as two copies of a player or a player and a watcher daemon,
+	// take turns: only one shows track notifications, and the others defer
+	// to it until it is closed. Linux only, through a D-Bus name.
+	// (default: false)
+	Coordinate bool
+
+	// DisableReconnect keeps the D-Bus backend on its first connection.
+	// By default, it reconnects when the session bus restarts, and
+	// forgets its notifications when the daemon restarts (e.g. dunst is
+	// restarted), so the next ones aren't sent as replacements for IDs the
+	// new daemon doesn't know. (default: false)
+	DisableReconnect bool
+
+	// Retry resends notifications the backend failed to deliver because it
+	// was momentarily busy or unreachable (errors wrapping ErrTimeout, such
+	// as D-Bus timeouts and no reply, or a push server not answering), with
+	// exponential backoff (default: 3 attempts, 100ms apart and doubling
+	// up to 1s)
+	Retry RetryPolicy
+
+	// RemoteRateLimit paces notifications to remote backends (see
+	// RemoteBackend), shared by every notifier sending to the same
+	// destination (default: bursts of 5, then one per second)
+	RemoteRateLimit RateLimit
+
+	// Logger receives debug logs of connection events, capability
+	// detection, suppressed notifications and delivery errors, to find out
+	// why a notification didn't show up (default: nil, nothing is logged)
+	Logger *slog.Logger
+
+	// LogFile is the file the "file" backend appends notifications to
+	LogFile string
+
+	// PushEndpoint is the UnifiedPush endpoint URL the "unifiedpush"
+	// backend posts to, as registered by the companion app on the phone.
+	// When empty, it is looked up in Credentials as "unifiedpush-endpoint".
+	PushEndpoint string
+
+	// Credentials supplies the secrets of remote backends, so they can be
+	// kept out of config files: "unifiedpush-endpoint", and
+	// "unifiedpush-token" for push servers requiring an access token
+	// (default: nil)
+	Credentials CredentialProvider
+
+	// Store persists state between runs (the notification to replace and
+	// the tracks already announced), see NewFileStore (default: nil,
+	// nothing is persisted)
+	Store Store
+
+	// Sampling thins out track change notifications, for noisy remote
+	// backends. Each Notifier delivers through one backend, so this is
+	// configured per backend. (default: every track)
+	Sampling SamplingPolicy
+
+	// ListenedAt is the fraction of a track (by Position and Duration)
+	// after which an EventListened is emitted, once per track. Only
+	// backends subscribing to it receive one. (default: 0.5, 0 = never)
+	ListenedAt float64
+}
+
+// SamplingPolicy decides which track changes are worth notifying
+type SamplingPolicy struct {
+	// EveryN posts only 1 in N track changes (0 or 1 = all)
+	EveryN int
+
+	// MinPlayed holds a track back until this fraction of it has been
+	// played (e.g. 0.5), computed from Position and Duration. Tracks
+	// without a Duration are never posted while it is set. (0 = at start)
+	MinPlayed float64
+}
+
+// RetryPolicy controls how transient delivery failures are retried
+type RetryPolicy struct {
+	// MaxAttempts is how many times a notification is sent before giving
+	// up, including the first (0 or 1 = no retries)
+	MaxAttempts int
+
+	// Backoff is the wait before the first retry, doubled after each one
+	Backoff time.Duration
+
+	// MaxBackoff caps the wait between retries (0 = no cap)
+	MaxBackoff time.Duration
+
+	// AttemptTimeout gives up on an attempt the D-Bus daemon hasn't
+	// answered within this long, counting it as a timeout (0 = the bus's
+	// own timeout, usually 25s)
+	AttemptTimeout time.Duration
+}
+
+// delay returns the wait before retry number attempt (1 for the first)
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff == 0 || delay < p.MaxBackoff); i++ {
+		delay *= 2
+	}
+	if p.MaxBackoff > 0 && delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
 }
 
 // DefaultOptions returns sensible defaults
@@ -38,5 +577,24 @@ func DefaultOptions(appName string) Options {
 		Timeout:         5000,
 		NotifyOnPause:   false,
 		ReplaceExisting: true,
+
+		SuppressDuringPresentation: false,
+		RespectDoNotDisturb:        true,
+		IconFallbacks:              []string{"media-playback-start", "audio-x-generic"},
+		ListenedAt:                 0.5,
+		MaxImageBytes:              1 << 20,
+		StaleAfter:                 30 * time.Second,
+		Retry: RetryPolicy{
+			MaxAttempts: 3,
+			Backoff:     100 * time.Millisecond,
+			MaxBackoff:  time.Second,
+		},
+		RemoteRateLimit: RateLimit{
+			Interval: time.Second,
+			Burst:    5,
+		},
+		PowerSaving: map[string]PowerSaving{
+			ProfilePowerSaver: {SkipArtDownloads: true, NoLiveUpdates: true},
+		},
 	}
 }
@@ -0,0 +1,259 @@
+package notifications
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultLiveInterval is how often live updates refresh the notification
+const defaultLiveInterval = time.Second
+
+// liveUpdates is a resident notification being refreshed by a ticker
+type liveUpdates struct {
+	id      uint32        // Notification being refreshed
+	stop    chan struct{} // Closed to stop the ticker
+	sending sync.Mutex    // Held by the ticker, after callMu, while it sends an update
+}
+
+// StartLiveUpdates shows a resident notification for track and keeps it
+// up to date with the elapsed time (and progress, where the daemon draws a
+// gauge) until StopLiveUpdates is called, another track is shown, the user
+// closes it, the track ends, Options.MaxPerTrack is used up or the system
+// starts saving power (see Options.PowerSaving). Playback is assumed to
+// continue from track.Position; call StopLiveUpdates when it pauses. The
+// backend must be able to replace notifications.
+func (n *Notifier) StartLiveUpdates(track *TrackInfo) error {
+	if n == nil {
+		return nil
+	}
+	if track == nil {
+		return fmt.Errorf("no track for live updates")
+	}
+	return n.startLive(track, n.liveInterval(), elapsedLine)
+}
+
+// StartCountdown is StartLiveUpdates showing the time left instead of the
+// elapsed time, e.g. "2:14 remaining". It is counted down from
+// track.Position on the monotonic clock, so it stays accurate without
+// further position updates from the player. The track needs a Duration.
+func (n *Notifier) StartCountdown(track *TrackInfo) error {
+	if n == nil {
+		return nil
+	}
+	if track == nil || track.Duration <= 0 {
+		return fmt.Errorf("no track duration to count down")
+	}
+	return n.startLive(track, n.liveInterval(), remainingLine)
+}
+
+// liveInterval returns how often live updates refresh
+func (n *Notifier) liveInterval() time.Duration {
+	if interval := n.opts().LiveUpdateInterval; interval > 0 {
+		return interval
+	}
+	return defaultLiveInterval
+}
+
+// elapsedLine formats the playback position, e.g. "1:23 / 3:45"
+func elapsedLine(track *TrackInfo) string {
+	line := formatDuration(track.Position)
+	if track.Duration > 0 {
+		line += " / " + formatDuration(track.Duration)
+	}
+	return line
+}
+
+// remainingLine formats the time left in the track, e.g. "2:14 remaining"
+func remainingLine(track *TrackInfo) string {
+	return formatDuration(max(track.Duration-track.Position, 0)) + " remaining"
+}
+
+// startLive starts live updates refreshing every interval, with a body line
+// formatted by line. Ticks that wouldn't change the line send nothing.
+func (n *Notifier) startLive(track *TrackInfo, interval time.Duration, line func(track *TrackInfo) string) error {
+	if !n.backend.Capabilities().Replacement {
+		return fmt.Errorf("%w: live updates need a backend that can replace notifications", ErrCapabilityMissing)
+	}
+	n.callMu.Lock()
+	defer n.callMu.Unlock()
+	n.stopLiveLocked()
+
+	start := time.Now()
+	base := *track.withSource()
+	appName, icon := n.identity(base.Source)
+
+	// The first update replaces the current track notification
+	n.mu.Lock()
+	replaceID := n.replaceID
+	n.mu.Unlock()
+
+	last := line(&base)
+	note, err := n.sendLive(&base, appName, icon, replaceID, last)
+	if err != nil {
+		return err
+	}
+	if note == nil || note.id == 0 {
+		return nil // Nothing to refresh
+	}
+	if n.powerSaving().NoLiveUpdates {
+		return nil // Shown once, saving power
+	}
+
+	live := &liveUpdates{
+		id:   note.id,
+		stop: make(chan struct{}),
+	}
+	n.liveMu.Lock()
+	n.live = live
+	n.liveMu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-live.stop:
+				return
+			case <-ticker.C:
+			}
+			if n.powerSaving().NoLiveUpdates {
+				n.stopLive(live, false)
+				return
+			}
+
+			track := base
+			track.Position += time.Since(start)
+			ended := track.Duration > 0 && track.Position >= track.Duration
+			if ended {
+				track.Position = track.Duration
+			}
+
+			current := line(&track)
+			if current == last && !ended {
+				continue
+			}
+			last = current
+			note, sent, err := n.tickLive(live, func() (*sentNotification, error) {
+				return n.sendLive(&track, appName, icon, live.id, current)
+			})
+			if !sent {
+				return
+			}
+			if note == nil || err != nil || ended {
+				n.stopLive(live, false)
+				return
+			}
+		}
+	}()
+
+	return nil
+}
+
+// tickLive sends one update of live with send, holding callMu like the
+// other methods sending notifications. It sends nothing, returning false,
+// if live was stopped while it waited for callMu.
+func (n *Notifier) tickLive(live *liveUpdates, send func() (*sentNotification, error)) (*sentNotification, bool, error) {
+	n.callMu.Lock()
+	defer n.callMu.Unlock()
+	live.sending.Lock()
+	defer live.sending.Unlock()
+
+	select {
+	case <-live.stop:
+		return nil, false, nil
+	default:
+	}
+	note, err := send()
+	return note, true, err
+}
+
+// StopLiveUpdates stops refreshing the live notification, leaving it as
+// last shown
+func (n *Notifier) StopLiveUpdates() {
+	if n == nil {
+		return
+	}
+	n.liveMu.Lock()
+	live := n.live
+	n.liveMu.Unlock()
+	if live != nil {
+		n.stopLive(live, true)
+	}
+}
+
+// stopLiveLocked stops the running live updates for a caller holding
+// callMu. It doesn't wait: the ticker can't be sending, and checks for the
+// stop once it gets callMu.
+func (n *Notifier) stopLiveLocked() {
+	n.liveMu.Lock()
+	live := n.live
+	n.liveMu.Unlock()
+	if live != nil {
+		n.stopLive(live, false)
+	}
+}
+
+// stopLive stops live, optionally waiting for an update being sent, so
+// none is sent once it returns. The ticker itself, signal handlers and
+// callers holding callMu must not wait.
+func (n *Notifier) stopLive(live *liveUpdates, wait bool) {
+	n.liveMu.Lock()
+	if n.live == live {
+		n.live = nil
+		close(live.stop)
+	}
+	n.liveMu.Unlock()
+
+	if wait {
+		live.sending.Lock()
+		live.sending.Unlock() // Only waiting for the ticker
+	}
+}
+
+// liveClosed stops live updates when the user closes their notification
+func (n *Notifier) liveClosed(id uint32) {
+	n.liveMu.Lock()
+	live := n.live
+	n.liveMu.Unlock()
+	if live != nil && live.id == id {
+		n.stopLive(live, false)
+	}
+}
+
+// sendLive renders and sends one live update of track, with line added to
+// the body. Updates count against Options.MaxPerTrack like other in-place
+// edits, returning a nil notification once the budget is spent. Callers
+// must hold callMu.
+func (n *Notifier) sendLive(track *TrackInfo, appName, icon string, replaceID uint32, line string) (*sentNotification, error) {
+	key := trackKey(track)
+	if !n.spendBudget(key) {
+		return nil, n.suppress(track, SuppressBudget)
+	}
+
+	caps := n.backend.Capabilities()
+	payload, err := withBodyLine(n.opts().renderer(caps), line).Render(track, StatePlaying)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render notification: %w", err)
+	}
+	payload.Actions = n.actions(payload.Actions, caps, StatePlaying)
+	payload = payload.degrade(caps)
+	if payload.Urgency == UrgencyNormal {
+		payload.Urgency = n.opts().Urgency
+	}
+	if payload.Icon != "" {
+		icon = payload.Icon // Resolving again per tick would walk the icon theme
+	}
+
+	note := &sentNotification{
+		appName: appName,
+		icon:    icon,
+		payload: payload,
+		track:   track,
+		state:   StatePlaying,
+		key:     key,
+		live:    true,
+	}
+	return note, n.send(note, replaceID)
+}
@@ -0,0 +1,95 @@
+package notifications_test
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-music-players/notifications"
+	"github.com/go-music-players/notifications/notificationstest"
+)
+
+// countingFake is a Fake counting every send, including those failing
+// once it is closed
+type countingFake struct {
+	*notificationstest.Fake
+	sends atomic.Int32
+}
+
+func (f *countingFake) Send(note *notifications.Notification) (uint32, error) {
+	f.sends.Add(1)
+	return f.Fake.Send(note)
+}
+
+// countingBackends numbers the backends openCounting registers
+var countingBackends atomic.Int32
+
+// openCounting opens a notifier on a new counting fake
+func openCounting(t *testing.T, opts ...notifications.Option) (*notifications.Notifier, *countingFake) {
+	t.Helper()
+	fake := &countingFake{Fake: notificationstest.NewFake()}
+	name := fmt.Sprintf("counting-fake-%d", countingBackends.Add(1))
+	notifications.Register(name, func(config notifications.BackendConfig) (notifications.Backend, error) {
+		if _, err := fake.Factory(config); err != nil {
+			return nil, err
+		}
+		return fake, nil
+	})
+	notifier, err := notifications.NewNotifier(append(opts, notifications.WithBackend(name))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { notifier.Close() })
+	return notifier, fake
+}
+
+func TestLiveUpdatesStop(t *testing.T) {
+	const interval = 2 * time.Millisecond
+	song := &notifications.TrackInfo{Title: "Song", Artist: "Band", Duration: time.Minute}
+	for i := 0; i < 1000; i++ {
+		song.Lyrics = append(song.Lyrics, notifications.LyricLine{At: time.Duration(i) * 5 * interval, Text: fmt.Sprint("Line ", i)})
+	}
+	other := &notifications.TrackInfo{Title: "Other", Artist: "Band"}
+
+	for _, tt := range []struct {
+		name      string
+		stop      func(n *notifications.Notifier) error
+		wantSends int32 // After stopping
+	}{
+		{"stop", func(n *notifications.Notifier) error { n.StopLiveUpdates(); return nil }, 0},
+		{"track change", func(n *notifications.Notifier) error { return n.Notify(other, notifications.StatePlaying) }, 1},
+		{"close", (*notifications.Notifier).Close, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier, fake := openCounting(t, notifications.WithOptions(func(o *notifications.Options) {
+				o.LiveUpdateInterval = interval
+				o.PowerSaving = nil // Whatever profile the machine is in
+			}))
+			if err := notifier.Notify(song, notifications.StatePlaying); err != nil {
+				t.Fatal(err)
+			}
+			if err := notifier.StartLyrics(song); err != nil {
+				t.Fatal(err)
+			}
+			for deadline := time.Now().Add(5 * time.Second); fake.sends.Load() < 5; time.Sleep(interval) {
+				if time.Now().After(deadline) {
+					t.Fatalf("sent %d updates, want some", fake.sends.Load())
+				}
+			}
+
+			before := fake.sends.Load()
+			if err := tt.stop(notifier); err != nil {
+				t.Fatal(err)
+			}
+			after := fake.sends.Load()
+			if after-before != tt.wantSends {
+				t.Errorf("stopping sent %d notifications, want %d", after-before, tt.wantSends)
+			}
+			time.Sleep(50 * interval)
+			if sends := fake.sends.Load(); sends != after {
+				t.Errorf("sent %d updates after stopping", sends-after)
+			}
+		})
+	}
+}
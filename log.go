@@ -0,0 +1,25 @@
+package notifications
+
+import (
+	"context"
+	"log/slog"
+)
+
+// discardLogger drops everything, for when Options.Logger is nil
+var discardLogger = slog.New(discardHandler{})
+
+// discardHandler is a slog.Handler that is never enabled
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+// logger returns the logger to use, never nil
+func (o *Options) logger() *slog.Logger {
+	if o.Logger == nil {
+		return discardLogger
+	}
+	return o.Logger
+}
@@ -0,0 +1,129 @@
+package notifications
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LyricLine is one line of synced lyrics
+type LyricLine struct {
+	At   time.Duration // When the line starts
+	Text string
+}
+
+// lrcTimestamp matches an LRC timestamp such as "[01:23.45]"
+var lrcTimestamp = regexp.MustCompile(`^\[(\d+):(\d{1,2})(?:[.:](\d{1,3}))?\]`)
+
+// ParseLRC parses synced lyrics in the LRC format, one "[mm:ss.xx]text"
+// per line. Lines may carry several timestamps; tags such as "[ar:...]"
+// and untimed lines are skipped. Returns nil for lyrics without timestamps.
+func ParseLRC(text string) []LyricLine {
+	var lines []LyricLine
+	for _, raw := range strings.Split(text, "\n") {
+		raw = strings.TrimSpace(raw)
+
+		var stamps []time.Duration
+		for {
+			m := lrcTimestamp.FindStringSubmatch(raw)
+			if m == nil {
+				break
+			}
+			minutes, _ := strconv.Atoi(m[1])
+			seconds, _ := strconv.Atoi(m[2])
+			at := time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second
+			if m[3] != "" {
+				// "[00:01.5]" is half a second, "[00:01.05]" a twentieth
+				fraction, _ := strconv.Atoi(m[3])
+				for i := len(m[3]); i < 3; i++ {
+					fraction *= 10
+				}
+				at += time.Duration(fraction) * time.Millisecond
+			}
+			stamps = append(stamps, at)
+			raw = raw[len(m[0]):]
+		}
+
+		for _, at := range stamps {
+			lines = append(lines, LyricLine{At: at, Text: strings.TrimSpace(raw)})
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].At < lines[j].At })
+	return lines
+}
+
+// lyricsAt returns the line being sung at position and the one after it
+// (empty before the first line and after the last)
+func lyricsAt(lines []LyricLine, position time.Duration) (current, next string) {
+	i := sort.Search(len(lines), func(i int) bool { return lines[i].At > position })
+	if i > 0 {
+		current = lines[i-1].Text
+	}
+	if i < len(lines) {
+		next = lines[i].Text
+	}
+	return current, next
+}
+
+// lyricsLine formats the current and next lyric lines for the body
+func lyricsLine(track *TrackInfo) string {
+	current, next := lyricsAt(track.Lyrics, track.Position)
+	if current == "" {
+		return next
+	}
+	if next == "" {
+		return "♪ " + current
+	}
+	return "♪ " + current + "\n" + next
+}
+
+const (
+	// defaultLyricsInterval is how often lyrics are checked for a new line
+	defaultLyricsInterval = 250 * time.Millisecond
+
+	// slowLyricsInterval is used with daemons that animate every update
+	slowLyricsInterval = time.Second
+)
+
+// lyricsIntervals are daemons that need lyrics updated less often, as
+// each update redraws or re-animates the popup
+var lyricsIntervals = map[string]time.Duration{
+	"notify-osd":    slowLyricsInterval,
+	"xfce4-notifyd": slowLyricsInterval,
+	"mako":          500 * time.Millisecond,
+}
+
+// StartLyrics shows a resident notification for track with the lyric line
+// being sung and the next one, updated as the track plays, until
+// StopLiveUpdates is called, another track is shown, the user closes it or
+// the track ends. It is live updates for track.Lyrics: playback is assumed
+// to continue from track.Position, and the backend must be able to replace
+// notifications. Updates are throttled per daemon unless
+// Options.LiveUpdateInterval is set, and each line counts against
+// Options.MaxPerTrack.
+func (n *Notifier) StartLyrics(track *TrackInfo) error {
+	if n == nil {
+		return nil
+	}
+	if track == nil || len(track.Lyrics) == 0 {
+		return fmt.Errorf("no synced lyrics")
+	}
+	return n.startLive(track, n.lyricsInterval(), lyricsLine)
+}
+
+// lyricsInterval returns how often lyrics are refreshed with this daemon
+func (n *Notifier) lyricsInterval() time.Duration {
+	if n.opts().LiveUpdateInterval > 0 {
+		return n.opts().LiveUpdateInterval
+	}
+	if info, err := n.ServerInfo(); err == nil {
+		if interval, ok := lyricsIntervals[info.Name]; ok {
+			return interval
+		}
+	}
+	return defaultLyricsInterval
+}
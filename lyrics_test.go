@@ -0,0 +1,95 @@
+package notifications
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseLRC(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		lrc  string
+		want []LyricLine
+	}{
+		{
+			name: "lines",
+			lrc:  "[00:01.00]First\n[00:02.50] Second \n",
+			want: []LyricLine{{time.Second, "First"}, {2500 * time.Millisecond, "Second"}},
+		},
+		{
+			name: "fractions",
+			lrc:  "[00:01.5]Tenths\n[00:02.05]Hundredths\n[00:03.005]Thousandths\n[00:04]Whole\n[00:05:25]Colon",
+			want: []LyricLine{
+				{1500 * time.Millisecond, "Tenths"},
+				{2050 * time.Millisecond, "Hundredths"},
+				{3005 * time.Millisecond, "Thousandths"},
+				{4 * time.Second, "Whole"},
+				{5250 * time.Millisecond, "Colon"},
+			},
+		},
+		{
+			name: "minutes",
+			lrc:  "[01:02.03]Later\n[123:00.00]Much later",
+			want: []LyricLine{{62030 * time.Millisecond, "Later"}, {123 * time.Minute, "Much later"}},
+		},
+		{
+			name: "several timestamps",
+			lrc:  "[00:01.00]Verse\n[00:02.00][00:04.00]Chorus\n[00:03.00]Bridge",
+			want: []LyricLine{{time.Second, "Verse"}, {2 * time.Second, "Chorus"}, {3 * time.Second, "Bridge"}, {4 * time.Second, "Chorus"}},
+		},
+		{
+			name: "tags and untimed lines skipped",
+			lrc:  "[ar:Band]\n[ti:Song]\n[offset:+100]\nUntimed\n\n[00:01.00]Sung",
+			want: []LyricLine{{time.Second, "Sung"}},
+		},
+		{
+			name: "instrumental break",
+			lrc:  "[00:01.00]Sung\n[00:02.00]\n[00:03.00]Again",
+			want: []LyricLine{{time.Second, "Sung"}, {2 * time.Second, ""}, {3 * time.Second, "Again"}},
+		},
+		{
+			name: "CRLF",
+			lrc:  "[00:01.00]First\r\n[00:02.00]Second\r\n",
+			want: []LyricLine{{time.Second, "First"}, {2 * time.Second, "Second"}},
+		},
+		{
+			name: "no timestamps",
+			lrc:  "Plain lyrics\nwithout timing",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseLRC(tt.lrc); !slices.Equal(got, tt.want) {
+				t.Errorf("ParseLRC = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLyricsLine(t *testing.T) {
+	lyrics := ParseLRC("[00:10.00]First\n[00:20.00]Second\n[00:30.00]Last")
+	for _, tt := range []struct {
+		name        string
+		position    time.Duration
+		wantCurrent string
+		wantNext    string
+		want        string
+	}{
+		{"before the first", 5 * time.Second, "", "First", "First"},
+		{"on the first", 10 * time.Second, "First", "Second", "♪ First\nSecond"},
+		{"between", 25 * time.Second, "Second", "Last", "♪ Second\nLast"},
+		{"on the last", 30 * time.Second, "Last", "", "♪ Last"},
+		{"after the last", time.Hour, "Last", "", "♪ Last"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			current, next := lyricsAt(lyrics, tt.position)
+			if current != tt.wantCurrent || next != tt.wantNext {
+				t.Errorf("lyricsAt = %q, %q, want %q, %q", current, next, tt.wantCurrent, tt.wantNext)
+			}
+			track := TrackInfo{Lyrics: lyrics, Position: tt.position}
+			if line := lyricsLine(&track); line != tt.want {
+				t.Errorf("lyricsLine = %q, want %q", line, tt.want)
+			}
+		})
+	}
+}
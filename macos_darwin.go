@@ -0,0 +1,254 @@
+//go:build darwin
+
+package notifications
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultBackend is used when Options.Backend is empty
+const defaultBackend = "macos"
+
+// defaultFallbacks are tried when the default backend can't be opened
+var defaultFallbacks []string
+
+func init() {
+	Register("macos", newMacOSBackend)
+}
+
+// displayScript shows a notification from its arguments, so nothing needs
+// to be escaped for AppleScript
+var displayScript = []string{
+	"-e", "on run argv",
+	"-e", "display notification (item 3 of argv) with title (item 1 of argv) subtitle (item 2 of argv)",
+	"-e", "end run",
+}
+
+// alerterResult is what alerter prints with -json when its notification
+// is clicked, closed or times out
+type alerterResult struct {
+	ActivationType  string `json:"activationType"` // "actionClicked", "contentsClicked", "closed" or "timeout"
+	ActivationValue string `json:"activationValue"`
+}
+
+// macOSBackend posts notifications through terminal-notifier when it is
+// installed, and osascript otherwise. terminal-notifier can replace and
+// remove notifications and show album art; osascript can only post them.
+// Notifications with actions are posted through alerter, terminal-notifier's
+// fork for buttons, when it is installed.
+type macOSBackend struct {
+	terminalNotifier string // Path to terminal-notifier, if installed
+	alerter          string // Path to alerter, if installed
+	osascript        string
+	config           BackendConfig
+
+	mu       sync.Mutex
+	nextID   uint32
+	watchers map[uint32]*exec.Cmd // alerter processes waiting for clicks
+}
+
+// newMacOSBackend looks up the helper tools
+func newMacOSBackend(config BackendConfig) (Backend, error) {
+	b := &macOSBackend{
+		config:   config,
+		nextID:   firstID(),
+		watchers: make(map[uint32]*exec.Cmd),
+	}
+	b.terminalNotifier, _ = exec.LookPath("terminal-notifier")
+	b.alerter, _ = exec.LookPath("alerter")
+	b.osascript, _ = exec.LookPath("osascript")
+	if b.terminalNotifier == "" && b.osascript == "" {
+		return nil, fmt.Errorf("macOS notifications need osascript or terminal-notifier")
+	}
+	return b, nil
+}
+
+// Send posts a notification with the summary as the title. A multi-line
+// body is split into a subtitle (the artist) and the message.
+func (b *macOSBackend) Send(note *Notification) (uint32, error) {
+	subtitle, message := "", note.Body
+	if first, rest, ok := strings.Cut(note.Body, "\n"); ok {
+		subtitle, message = first, rest
+	}
+
+	if b.terminalNotifier == "" && (b.alerter == "" || len(note.Actions) == 0) {
+		args := append(append([]string{}, displayScript...), note.Summary, subtitle, message)
+		return 0, runTool(b.osascript, args...)
+	}
+
+	id := note.ReplacesID
+	if id == 0 {
+		b.mu.Lock()
+		b.nextID++
+		id = b.nextID
+		b.mu.Unlock()
+	}
+
+	if message == "" {
+		message = " " // terminal-notifier reads stdin without a message
+	}
+	args := []string{
+		"-title", note.Summary,
+		"-message", message,
+		"-group", strconv.FormatUint(uint64(id), 10), // Same group replaces
+	}
+	if subtitle != "" {
+		args = append(args, "-subtitle", subtitle)
+	}
+	if note.ImagePath != "" {
+		args = append(args, "-contentImage", note.ImagePath)
+	}
+
+	// The replaced notification's clicks are no longer news
+	b.unwatch(id)
+	if b.alerter != "" && len(note.Actions) > 0 {
+		if err := b.watch(id, note, args); err != nil {
+			return 0, err
+		}
+		return id, nil
+	}
+	if err := runTool(b.terminalNotifier, args...); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Dismiss removes a notification; only possible with terminal-notifier or
+// alerter
+func (b *macOSBackend) Dismiss(id uint32) error {
+	b.unwatch(id)
+	tool := b.terminalNotifier
+	if tool == "" {
+		tool = b.alerter
+	}
+	if tool == "" {
+		return nil
+	}
+	return runTool(tool, "-remove", strconv.FormatUint(uint64(id), 10))
+}
+
+// Capabilities returns what the available tools support
+func (b *macOSBackend) Capabilities() Capabilities {
+	if b.terminalNotifier == "" {
+		return Capabilities{Actions: b.alerter != ""}
+	}
+	return Capabilities{
+		Images:      true,
+		Actions:     b.alerter != "",
+		Replacement: true,
+	}
+}
+
+// Close stops waiting for clicks on the notifications posted
+func (b *macOSBackend) Close() error {
+	b.mu.Lock()
+	watchers := b.watchers
+	b.watchers = make(map[uint32]*exec.Cmd)
+	b.mu.Unlock()
+	for _, cmd := range watchers {
+		cmd.Process.Kill()
+	}
+	return nil
+}
+
+// watch posts a notification with buttons through alerter, which runs
+// until the notification is clicked or closed and then prints the result
+func (b *macOSBackend) watch(id uint32, note *Notification, args []string) error {
+	var labels []string
+	for _, action := range note.Actions {
+		if action.ID != "default" {
+			// alerter separates the labels with commas
+			labels = append(labels, strings.ReplaceAll(action.Label, ",", ""))
+		}
+	}
+	args = append(args, "-json")
+	if len(labels) > 0 {
+		args = append(args, "-actions", strings.Join(labels, ","))
+	}
+	if note.Timeout > 0 {
+		args = append(args, "-timeout", strconv.Itoa(int((note.Timeout+999)/1000)))
+	}
+
+	cmd := exec.Command(b.alerter, args...)
+	var stdout strings.Builder
+	cmd.Stdout = &stdout
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("%s: %w", b.alerter, err)
+	}
+	b.mu.Lock()
+	b.watchers[id] = cmd
+	b.mu.Unlock()
+
+	go func() {
+		err := cmd.Wait()
+		b.mu.Lock()
+		current := b.watchers[id] == cmd
+		if current {
+			delete(b.watchers, id)
+		}
+		b.mu.Unlock()
+		if current && err == nil {
+			b.report(id, note.Actions, stdout.String())
+		}
+	}()
+	return nil
+}
+
+// report passes on what alerter printed for notification id
+func (b *macOSBackend) report(id uint32, actions []Action, output string) {
+	var result alerterResult
+	if err := json.Unmarshal([]byte(output), &result); err != nil {
+		return
+	}
+
+	reason := CloseDismissed
+	switch result.ActivationType {
+	case "actionClicked":
+		for _, action := range actions {
+			if action.ID != "default" && strings.ReplaceAll(action.Label, ",", "") == result.ActivationValue {
+				b.invoke(id, action.ID)
+				break
+			}
+		}
+	case "contentsClicked":
+		b.invoke(id, "default")
+	case "timeout":
+		reason = CloseExpired
+	}
+	// The notification is gone once alerter exits
+	if b.config.OnClosed != nil {
+		b.config.OnClosed(id, reason)
+	}
+}
+
+// invoke reports a click on action key of notification id
+func (b *macOSBackend) invoke(id uint32, key string) {
+	if b.config.OnAction != nil {
+		b.config.OnAction(id, key)
+	}
+}
+
+// unwatch stops waiting for clicks on notification id
+func (b *macOSBackend) unwatch(id uint32) {
+	b.mu.Lock()
+	cmd := b.watchers[id]
+	delete(b.watchers, id)
+	b.mu.Unlock()
+	if cmd != nil {
+		cmd.Process.Kill()
+	}
+}
+
+// runTool executes a helper tool, including its output in errors
+func runTool(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
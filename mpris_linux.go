@@ -0,0 +1,265 @@
+//go:build linux
+
+package notifications
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	mprisPrefix          = "org.mpris.MediaPlayer2."
+	mprisPath            = "/org/mpris/MediaPlayer2"
+	mprisPlayerInterface = "org.mpris.MediaPlayer2.Player"
+)
+
+// Watcher follows MPRIS media players on the session bus and drives a
+// Notifier whenever one of them changes track or playback state, so no
+// polling is needed
+type Watcher struct {
+	conn     *dbus.Conn
+	notifier *Notifier
+
+	mu      sync.Mutex
+	owners  map[string]string       // Player bus name by unique connection name
+	players map[string]*mprisPlayer // Player state by bus name
+}
+
+// mprisPlayer is the last known state of one player
+type mprisPlayer struct {
+	track TrackInfo
+	state PlaybackState
+}
+
+// NewWatcher starts watching MPRIS players. The watcher calls
+// notifier.Notify from its own goroutine until Close is called.
+func NewWatcher(notifier *Notifier) (*Watcher, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to session bus: %w", diagnoseConnect(err))
+	}
+
+	w := &Watcher{
+		conn:     conn,
+		notifier: notifier,
+		owners:   make(map[string]string),
+		players:  make(map[string]*mprisPlayer),
+	}
+
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+
+	matches := [][]dbus.MatchOption{
+		{
+			dbus.WithMatchObjectPath(mprisPath),
+			dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+			dbus.WithMatchMember("PropertiesChanged"),
+			dbus.WithMatchArg(0, mprisPlayerInterface),
+		},
+		{
+			dbus.WithMatchSender("org.freedesktop.DBus"),
+			dbus.WithMatchInterface("org.freedesktop.DBus"),
+			dbus.WithMatchMember("NameOwnerChanged"),
+			dbus.WithMatchArg0Namespace("org.mpris.MediaPlayer2"),
+		},
+	}
+	for _, match := range matches {
+		if err := conn.AddMatchSignal(match...); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to subscribe to MPRIS signals: %w", err)
+		}
+	}
+
+	// Pick up players that are already running
+	var names []string
+	if err := conn.BusObject().Call("org.freedesktop.DBus.ListNames", 0).Store(&names); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to list players: %w", err)
+	}
+
+	// Clicking a notification raises the player it is about
+	notifier.setRaiser(func(track *TrackInfo) { w.Raise(track) })
+
+	for _, name := range names {
+		if strings.HasPrefix(name, mprisPrefix) {
+			w.addPlayer(name)
+		}
+	}
+
+	go w.run(signals)
+
+	return w, nil
+}
+
+// Close stops watching
+func (w *Watcher) Close() error {
+	w.notifier.setRaiser(nil)
+	return w.conn.Close()
+}
+
+// Raise brings the player that reported track to the front
+func (w *Watcher) Raise(track *TrackInfo) error {
+	if track == nil || track.Source == "" {
+		return fmt.Errorf("no player to raise")
+	}
+	obj := w.conn.Object(mprisPrefix+track.Source, mprisPath)
+	if call := obj.Call("org.mpris.MediaPlayer2.Raise", 0); call.Err != nil {
+		return fmt.Errorf("failed to raise player: %w", call.Err)
+	}
+	return nil
+}
+
+// run dispatches signals until the connection is closed
+func (w *Watcher) run(signals chan *dbus.Signal) {
+	for signal := range signals {
+		switch signal.Name {
+		case "org.freedesktop.DBus.Properties.PropertiesChanged":
+			w.propertiesChanged(signal)
+		case "org.freedesktop.DBus.NameOwnerChanged":
+			w.nameOwnerChanged(signal)
+		}
+	}
+}
+
+// addPlayer starts tracking a player and fetches its current state
+func (w *Watcher) addPlayer(name string) {
+	var owner string
+	if err := w.conn.BusObject().Call("org.freedesktop.DBus.GetNameOwner", 0, name).Store(&owner); err != nil {
+		return
+	}
+
+	var props map[string]dbus.Variant
+	obj := w.conn.Object(name, mprisPath)
+	if err := obj.Call("org.freedesktop.DBus.Properties.GetAll", 0, mprisPlayerInterface).Store(&props); err != nil {
+		return
+	}
+
+	w.mu.Lock()
+	w.owners[owner] = name
+	player := &mprisPlayer{state: StateStopped}
+	player.track.Source = strings.TrimPrefix(name, mprisPrefix)
+	player.track.Origin = mprisOrigin(player.track.Source)
+	w.players[name] = player
+	w.mu.Unlock()
+
+	w.update(name, props)
+}
+
+// nameOwnerChanged follows players starting and quitting
+func (w *Watcher) nameOwnerChanged(signal *dbus.Signal) {
+	if len(signal.Body) < 3 {
+		return
+	}
+	name, _ := signal.Body[0].(string)
+	oldOwner, _ := signal.Body[1].(string)
+	newOwner, _ := signal.Body[2].(string)
+
+	if oldOwner != "" {
+		w.mu.Lock()
+		player := w.players[name]
+		delete(w.owners, oldOwner)
+		delete(w.players, name)
+		w.mu.Unlock()
+
+		// A player quitting stops its playback
+		if player != nil && player.state != StateStopped && w.notifier.clearsOnStop() {
+			w.notifier.Notify(&player.track, StateStopped)
+		}
+	}
+	if newOwner != "" {
+		w.addPlayer(name)
+	}
+}
+
+// propertiesChanged applies a player's changed properties
+func (w *Watcher) propertiesChanged(signal *dbus.Signal) {
+	if len(signal.Body) < 2 {
+		return
+	}
+	changed, ok := signal.Body[1].(map[string]dbus.Variant)
+	if !ok {
+		return
+	}
+
+	w.mu.Lock()
+	name, ok := w.owners[signal.Sender]
+	w.mu.Unlock()
+	if !ok {
+		return // Not a player we know about
+	}
+
+	w.update(name, changed)
+}
+
+// update merges properties into a player's state and notifies
+func (w *Watcher) update(name string, props map[string]dbus.Variant) {
+	w.mu.Lock()
+	player, ok := w.players[name]
+	if !ok {
+		w.mu.Unlock()
+		return
+	}
+	if v, ok := props["Metadata"]; ok {
+		if metadata, ok := v.Value().(map[string]dbus.Variant); ok {
+			player.track = metadataTrack(metadata, player.track.Source)
+		}
+	}
+	if v, ok := props["PlaybackStatus"]; ok {
+		if status, ok := v.Value().(string); ok {
+			player.state = PlaybackState(status)
+		}
+	}
+	track, state := player.track, player.state
+	w.mu.Unlock()
+
+	if state == StateStopped && !w.notifier.clearsOnStop() {
+		return
+	}
+	w.notifier.Notify(&track, state)
+}
+
+// metadataTrack converts MPRIS metadata to a TrackInfo
+func metadataTrack(metadata map[string]dbus.Variant, source string) TrackInfo {
+	track := TrackInfo{Source: source, Origin: mprisOrigin(source)}
+
+	if v, ok := metadata["xesam:title"].Value().(string); ok {
+		track.Title = v
+	}
+	if v, ok := metadata["xesam:artist"].Value().([]string); ok {
+		track.Artist = strings.Join(v, ", ")
+	}
+	if v, ok := metadata["xesam:album"].Value().(string); ok {
+		track.Album = v
+	}
+	if v, ok := metadata["xesam:trackNumber"].Value().(int32); ok {
+		track.TrackNumber = int(v)
+	}
+	if v, ok := metadata["mpris:artUrl"].Value().(string); ok {
+		track.ImageURL = v
+	}
+	// Players with synced lyrics send them as LRC
+	if v, ok := metadata["xesam:asText"].Value().(string); ok {
+		track.Lyrics = ParseLRC(v)
+	}
+
+	// mpris:length is in microseconds; players disagree on its type
+	switch v := metadata["mpris:length"].Value().(type) {
+	case int64:
+		track.Duration = time.Duration(v) * time.Microsecond
+	case uint64:
+		track.Duration = time.Duration(v) * time.Microsecond
+	}
+
+	return track
+}
+
+// mprisOrigin splits a player's bus name suffix into the player and, for
+// players running several instances, the instance ("vlc.instance1234")
+func mprisOrigin(source string) Origin {
+	player, instance, _ := strings.Cut(source, ".")
+	return Origin{Player: player, Instance: instance}
+}
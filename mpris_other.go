@@ -0,0 +1,23 @@
+//go:build !linux
+
+package notifications
+
+import "fmt"
+
+// Watcher stub for non-Linux platforms
+type Watcher struct{}
+
+// NewWatcher returns an error on non-Linux platforms
+func NewWatcher(notifier *Notifier) (*Watcher, error) {
+	return nil, fmt.Errorf("MPRIS is only available on Linux")
+}
+
+// Close is a no-op on non-Linux platforms
+func (w *Watcher) Close() error {
+	return nil
+}
+
+// Raise returns an error on non-Linux platforms
+func (w *Watcher) Raise(track *TrackInfo) error {
+	return fmt.Errorf("MPRIS is only available on Linux")
+}
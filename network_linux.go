@@ -0,0 +1,114 @@
+//go:build linux
+
+package notifications
+
+import (
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	networkManagerService = "org.freedesktop.NetworkManager"
+	networkManagerPath    = "/org/freedesktop/NetworkManager"
+)
+
+// NetworkManager's connectivity states
+const (
+	connectivityUnknown = 0
+	connectivityNone    = 1
+	connectivityFull    = 4
+)
+
+// connectivity follows NetworkManager's view of whether the internet is
+// reachable, so downloads can fail fast while offline instead of waiting
+// for the HTTP timeout on every track change
+type connectivity struct {
+	conn *dbus.Conn
+
+	mu    sync.Mutex
+	state uint32
+}
+
+// watchConnectivity subscribes to NetworkManager on the system bus. It
+// returns nil when NetworkManager isn't available, and a nil connectivity
+// always reports being online.
+func watchConnectivity() *connectivity {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil
+	}
+
+	err = conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(networkManagerPath),
+		dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+		dbus.WithMatchMember("PropertiesChanged"),
+		dbus.WithMatchArg(0, networkManagerService),
+	)
+	if err != nil {
+		conn.Close()
+		return nil
+	}
+
+	// Read the state after subscribing so no change is missed in between
+	obj := conn.Object(networkManagerService, networkManagerPath)
+	v, err := obj.GetProperty(networkManagerService + ".Connectivity")
+	if err != nil {
+		conn.Close()
+		return nil
+	}
+
+	c := &connectivity{conn: conn}
+	c.state, _ = v.Value().(uint32)
+
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+	go func() {
+		// The channel is closed when the connection is closed
+		for signal := range signals {
+			if len(signal.Body) < 2 {
+				continue
+			}
+			changed, _ := signal.Body[1].(map[string]dbus.Variant)
+			if state, ok := changed["Connectivity"].Value().(uint32); ok {
+				c.mu.Lock()
+				c.state = state
+				c.mu.Unlock()
+			}
+		}
+	}()
+
+	return c
+}
+
+// online reports whether the internet is reachable. Behind a captive
+// portal or with only local connectivity, downloads would fail anyway.
+func (c *connectivity) online() bool {
+	if c == nil {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state == connectivityUnknown || c.state == connectivityFull
+}
+
+// reachable reports whether host can be downloaded from. Hosts on the
+// local network stay reachable behind a captive portal or with only local
+// connectivity, which NetworkManager also reports for LANs without a
+// route to the internet.
+func (c *connectivity) reachable(host string) bool {
+	if c.online() {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state != connectivityNone && localHost(host)
+}
+
+// close stops watching
+func (c *connectivity) close() error {
+	if c == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
@@ -0,0 +1,26 @@
+//go:build !linux
+
+package notifications
+
+// connectivity is only tracked through NetworkManager on Linux
+type connectivity struct{}
+
+// watchConnectivity returns nil, which always reports being online
+func watchConnectivity() *connectivity {
+	return nil
+}
+
+// online reports whether the internet is reachable
+func (c *connectivity) online() bool {
+	return true
+}
+
+// reachable reports whether host can be downloaded from
+func (c *connectivity) reachable(host string) bool {
+	return true
+}
+
+// close stops watching
+func (c *connectivity) close() error {
+	return nil
+}
@@ -0,0 +1,104 @@
+package notifications
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// NormalizeRules selects the fixes NormalizeEnricher applies.
+// The rules are deliberately conservative to avoid mangling real names.
+type NormalizeRules struct {
+	FixShouting   bool // "THE BEATLES" → "The Beatles" (single-word names like "ABBA" are kept)
+	NormalizeFeat bool // "ft.", "Ft", "featuring" → "feat."
+	CollapseSpace bool // Trim and collapse runs of whitespace
+}
+
+// DefaultNormalizeRules enables every rule
+func DefaultNormalizeRules() NormalizeRules {
+	return NormalizeRules{
+		FixShouting:   true,
+		NormalizeFeat: true,
+		CollapseSpace: true,
+	}
+}
+
+// featPattern matches the common spellings of "featuring"
+var featPattern = regexp.MustCompile(`(?i)\b(?:feat|ft|featuring)\b\.?\s+`)
+
+// NormalizeEnricher returns an Enricher that cleans up the title, artist and
+// album of scraped metadata, as commonly seen on web radio
+func NormalizeEnricher(rules NormalizeRules) Enricher {
+	return func(ctx context.Context, track *TrackInfo) error {
+		for _, field := range []*string{&track.Title, &track.Artist, &track.Album} {
+			*field = normalizeField(*field, rules)
+		}
+		return nil
+	}
+}
+
+// normalizeField applies rules to a single metadata field
+func normalizeField(s string, rules NormalizeRules) string {
+	if rules.CollapseSpace {
+		s = collapseSpace(s)
+	}
+	if rules.FixShouting && isShouting(featPattern.ReplaceAllString(s, "")) {
+		s = titleCase(s)
+	}
+	if rules.NormalizeFeat {
+		s = featPattern.ReplaceAllString(s, "feat. ")
+	}
+	return s
+}
+
+// isShouting reports whether s is all upper case and has at least two words,
+// so acronyms and stylised single-word names are left alone
+func isShouting(s string) bool {
+	words := 0
+	for _, word := range strings.Fields(s) {
+		letters := 0
+		for _, r := range word {
+			if unicode.IsLetter(r) {
+				if !unicode.IsUpper(r) {
+					return false
+				}
+				letters++
+			}
+		}
+		if letters >= 2 {
+			words++
+		}
+	}
+	return words >= 2
+}
+
+// titleCase capitalises the first letter of each word and lowercases the
+// rest, keeping Roman numerals ("PART II") intact
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, word := range words {
+		if isRomanNumeral(word) {
+			continue
+		}
+
+		runes := []rune(strings.ToLower(word))
+		for j, r := range runes {
+			if unicode.IsLetter(r) {
+				runes[j] = unicode.ToUpper(r)
+				break
+			}
+		}
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+// isRomanNumeral reports whether word is a short Roman numeral like "II" or "XIV"
+func isRomanNumeral(word string) bool {
+	word = strings.Trim(word, "().,")
+	if word == "" || len(word) > 4 {
+		return false
+	}
+	return strings.Trim(word, "IVXLC") == ""
+}
@@ -0,0 +1,30 @@
+package notifications
+
+import "testing"
+
+func TestNormalizeField(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"Song", "Song"},
+		{"  Song   Title ", "Song Title"},
+		{"THE BEATLES", "The Beatles"},
+		{"ABBA", "ABBA"},
+		{"PART II", "Part II"},
+		{"Artist ft. Guest", "Artist feat. Guest"},
+		{"Artist Featuring Guest", "Artist feat. Guest"},
+		{"ARTIST FT GUEST", "Artist feat. Guest"},
+		{"Left Feather", "Left Feather"},
+	} {
+		if got := normalizeField(tt.in, DefaultNormalizeRules()); got != tt.want {
+			t.Errorf("normalizeField(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeRulesDisabled(t *testing.T) {
+	const in = "  THE BEATLES ft.  GUEST"
+	if got := normalizeField(in, NormalizeRules{}); got != in {
+		t.Errorf("normalizeField(%q) with no rules = %q, want it unchanged", in, got)
+	}
+}
@@ -0,0 +1,316 @@
+package notificationstest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-music-players/notifications"
+)
+
+// Call is one notification a Fake was sent
+type Call struct {
+	ID         uint32 // ID the Fake assigned
+	ReplacesID uint32 // Notification it replaced (0 for a new one)
+
+	Track *notifications.TrackInfo    // Track it is about (nil for messages)
+	State notifications.PlaybackState // Playback state it shows (empty for messages)
+	Event notifications.Event
+
+	AppName string
+	Icon    string
+	Summary string
+	Body    string   // As rendered for the Fake's capabilities
+	Actions []string // Action IDs, in order
+	Timeout int32
+
+	// Hints are the notification's D-Bus hints, as the D-Bus backend
+	// would send them: "urgency" (0 low, 2 critical; absent for normal),
+	// "desktop-entry", "transient", "resident", "value", "sound-name",
+	// "sound-file", "suppress-sound" and "image-path"
+	Hints map[string]any
+
+	Notification *notifications.Notification // As sent
+}
+
+// Fake is a notifications.Backend that records every notification it is
+// sent, for unit tests of code that notifies. It behaves like a daemon
+// that supports everything unless told otherwise with SetCapabilities,
+// and can invoke actions and close notifications as a user would.
+type Fake struct {
+	mu        sync.Mutex
+	caps      notifications.Capabilities
+	config    notifications.BackendConfig
+	calls     []Call
+	nextID    uint32
+	open      map[uint32]bool // Notifications not closed yet
+	dismissed []uint32
+	closed    bool
+}
+
+// NewFake creates a fake supporting art, actions, replacement, markup and
+// progress gauges
+func NewFake() *Fake {
+	return &Fake{
+		caps: notifications.Capabilities{
+			Images:      true,
+			Actions:     true,
+			Replacement: true,
+			Markup:      true,
+			Progress:    true,
+		},
+		open: make(map[uint32]bool),
+	}
+}
+
+// fakeBackend is the backend name NewNotifier opens fakes under
+const fakeBackend = "notificationstest-fake"
+
+// The fake NewNotifier is opening, for the factory registered once under
+// fakeBackend. openMu serializes opening notifiers.
+var (
+	registerOnce sync.Once
+	openMu       sync.Mutex
+	opening      *Fake
+)
+
+// NewNotifier opens a notifier delivering to fake. The options apply as
+// with notifications.NewNotifier, except for the backend.
+func NewNotifier(fake *Fake, opts ...notifications.Option) (*notifications.Notifier, error) {
+	registerOnce.Do(func() {
+		notifications.Register(fakeBackend, func(config notifications.BackendConfig) (notifications.Backend, error) {
+			if opening == nil {
+				return nil, fmt.Errorf("notificationstest: open fakes with NewNotifier")
+			}
+			return opening.Factory(config)
+		})
+	})
+
+	openMu.Lock()
+	defer openMu.Unlock()
+	opening = fake
+	defer func() { opening = nil }()
+	return notifications.NewNotifier(append(slices.Clip(opts), notifications.WithBackend(fakeBackend))...)
+}
+
+// Factory is a notifications.BackendFactory opening the fake, for
+// registering it under a name of your choice
+func (f *Fake) Factory(config notifications.BackendConfig) (notifications.Backend, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.config = config
+	f.closed = false
+	return f, nil
+}
+
+// SetCapabilities changes what the fake supports, e.g. to test fallbacks
+// for daemons without actions
+func (f *Fake) SetCapabilities(caps notifications.Capabilities) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.caps = caps
+}
+
+// Send records the notification
+func (f *Fake) Send(note *notifications.Notification) (uint32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, fmt.Errorf("notificationstest: backend closed")
+	}
+
+	id := note.ReplacesID
+	if id == 0 || !f.open[id] || !f.caps.Replacement {
+		f.nextID++
+		id = f.nextID
+	}
+	f.open[id] = true
+
+	call := Call{
+		ID:           id,
+		ReplacesID:   note.ReplacesID,
+		Track:        note.Track,
+		State:        note.State,
+		Event:        note.Event,
+		AppName:      note.AppName,
+		Icon:         note.Icon,
+		Summary:      note.Summary,
+		Body:         note.Body,
+		Timeout:      note.Timeout,
+		Hints:        hints(note),
+		Notification: note,
+	}
+	for _, action := range note.Actions {
+		call.Actions = append(call.Actions, action.ID)
+	}
+	f.calls = append(f.calls, call)
+	return id, nil
+}
+
+// hints returns the D-Bus hints for a notification
+func hints(note *notifications.Notification) map[string]any {
+	hints := make(map[string]any)
+	switch note.Urgency {
+	case notifications.UrgencyLow:
+		hints["urgency"] = byte(0)
+	case notifications.UrgencyCritical:
+		hints["urgency"] = byte(2)
+	}
+	if note.DesktopEntry != "" {
+		hints["desktop-entry"] = note.DesktopEntry
+	}
+	if note.Transient {
+		hints["transient"] = true
+	}
+	if note.Resident {
+		hints["resident"] = true
+	}
+	if note.Progress >= 0 {
+		hints["value"] = int32(note.Progress)
+	}
+	if note.SoundName != "" {
+		hints["sound-name"] = note.SoundName
+	}
+	if note.SoundFile != "" {
+		hints["sound-file"] = note.SoundFile
+	}
+	if note.SuppressSound {
+		hints["suppress-sound"] = true
+	}
+	if note.ImagePath != "" {
+		hints["image-path"] = "file://" + note.ImagePath
+	}
+	return hints
+}
+
+// Capabilities returns what the fake supports
+func (f *Fake) Capabilities() notifications.Capabilities {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.caps
+}
+
+// Dismiss closes a notification, recording it
+func (f *Fake) Dismiss(id uint32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.open, id)
+	f.dismissed = append(f.dismissed, id)
+	return nil
+}
+
+// Close makes later sends fail
+func (f *Fake) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	return nil
+}
+
+// Calls returns the notifications sent, in order
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call{}, f.calls...)
+}
+
+// Last returns the notification sent last, and false if there was none
+func (f *Fake) Last() (Call, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.calls) == 0 {
+		return Call{}, false
+	}
+	return f.calls[len(f.calls)-1], true
+}
+
+// Dismissed returns the IDs of the notifications closed through the
+// notifier, in order
+func (f *Fake) Dismissed() []uint32 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]uint32{}, f.dismissed...)
+}
+
+// Reset forgets the calls recorded so far
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+	f.dismissed = nil
+}
+
+// Invoke clicks action actionID on notification id, as a user would
+func (f *Fake) Invoke(id uint32, actionID string) {
+	f.mu.Lock()
+	onAction := f.config.OnAction
+	f.mu.Unlock()
+	if onAction != nil {
+		onAction(id, actionID)
+	}
+}
+
+// CloseNotification closes notification id as the daemon would, e.g.
+// with notifications.CloseDismissed for the user closing it
+func (f *Fake) CloseNotification(id uint32, reason notifications.CloseReason) {
+	f.mu.Lock()
+	delete(f.open, id)
+	onClosed := f.config.OnClosed
+	f.mu.Unlock()
+	if onClosed != nil {
+		onClosed(id, reason)
+	}
+}
+
+// AssertCount fails the test unless exactly n notifications were sent
+func (f *Fake) AssertCount(t testing.TB, n int) {
+	t.Helper()
+	if calls := f.Calls(); len(calls) != n {
+		t.Errorf("sent %d notifications, want %d%s", len(calls), n, list(calls))
+	}
+}
+
+// AssertNone fails the test if any notification was sent
+func (f *Fake) AssertNone(t testing.TB) {
+	t.Helper()
+	f.AssertCount(t, 0)
+}
+
+// AssertLast fails the test unless the notification sent last has this
+// summary and body
+func (f *Fake) AssertLast(t testing.TB, summary, body string) {
+	t.Helper()
+	last, ok := f.Last()
+	switch {
+	case !ok:
+		t.Errorf("no notification sent, want %q", summary)
+	case last.Summary != summary || last.Body != body:
+		t.Errorf("last notification is %q / %q, want %q / %q", last.Summary, last.Body, summary, body)
+	}
+}
+
+// AssertShown fails the test unless some notification had this summary
+func (f *Fake) AssertShown(t testing.TB, summary string) {
+	t.Helper()
+	calls := f.Calls()
+	for _, call := range calls {
+		if call.Summary == summary {
+			return
+		}
+	}
+	t.Errorf("no notification %q sent%s", summary, list(calls))
+}
+
+// list formats the summaries of calls for failure messages
+func list(calls []Call) string {
+	if len(calls) == 0 {
+		return ""
+	}
+	summaries := make([]string, len(calls))
+	for i, call := range calls {
+		summaries[i] = fmt.Sprintf("%q", call.Summary)
+	}
+	return "; sent " + strings.Join(summaries, ", ")
+}
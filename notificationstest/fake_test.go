@@ -0,0 +1,188 @@
+package notificationstest_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/go-music-players/notifications"
+	"github.com/go-music-players/notifications/notificationstest"
+)
+
+func TestFakeRecordsNotifications(t *testing.T) {
+	fake := notificationstest.NewFake()
+	notifier, err := notificationstest.NewNotifier(fake, notifications.WithUrgency(notifications.UrgencyLow))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer notifier.Close()
+
+	notifier.Notify(&notifications.TrackInfo{Title: "Song", Artist: "Band"}, notifications.StatePlaying)
+	fake.AssertCount(t, 1)
+	fake.AssertLast(t, "Song", "<b>Band</b>")
+
+	last, _ := fake.Last()
+	if last.State != notifications.StatePlaying {
+		t.Errorf("state = %q, want %q", last.State, notifications.StatePlaying)
+	}
+	if last.Track == nil || last.Track.Title != "Song" {
+		t.Errorf("track = %+v, want the track notified", last.Track)
+	}
+	if urgency := last.Hints["urgency"]; urgency != byte(0) {
+		t.Errorf("urgency hint = %v, want 0", urgency)
+	}
+}
+
+func TestFakeReplacement(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		caps    notifications.Capabilities
+		wantIDs []uint32
+	}{
+		{"replaces", notifications.Capabilities{Replacement: true}, []uint32{1, 1}},
+		{"no replacement", notifications.Capabilities{}, []uint32{1, 2}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := notificationstest.NewFake()
+			fake.SetCapabilities(tt.caps)
+			notifier, err := notificationstest.NewNotifier(fake)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer notifier.Close()
+
+			notifier.Notify(&notifications.TrackInfo{Title: "One"}, notifications.StatePlaying)
+			notifier.Notify(&notifications.TrackInfo{Title: "Two"}, notifications.StatePlaying)
+			calls := fake.Calls()
+			if len(calls) != len(tt.wantIDs) {
+				t.Fatalf("sent %d notifications, want %d", len(calls), len(tt.wantIDs))
+			}
+			for i, call := range calls {
+				if call.ID != tt.wantIDs[i] {
+					t.Errorf("notification %d has ID %d, want %d", i+1, call.ID, tt.wantIDs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFakeActions(t *testing.T) {
+	var invoked []string
+	next := notifications.NextAction(func(track *notifications.TrackInfo) {
+		invoked = append(invoked, track.Title)
+	})
+
+	for _, tt := range []struct {
+		name        string
+		caps        notifications.Capabilities
+		wantActions int
+	}{
+		{"with actions", notifications.Capabilities{Actions: true}, 1},
+		{"without actions", notifications.Capabilities{}, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			invoked = nil
+			fake := notificationstest.NewFake()
+			fake.SetCapabilities(tt.caps)
+			notifier, err := notificationstest.NewNotifier(fake, notifications.WithActions(next))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer notifier.Close()
+
+			notifier.Notify(&notifications.TrackInfo{Title: "Song"}, notifications.StatePlaying)
+			last, _ := fake.Last()
+			if len(last.Actions) != tt.wantActions {
+				t.Fatalf("actions = %v, want %d", last.Actions, tt.wantActions)
+			}
+			fake.Invoke(last.ID, "next")
+			if tt.wantActions > 0 && (len(invoked) != 1 || invoked[0] != "Song") {
+				t.Errorf("handler called for %v, want [Song]", invoked)
+			}
+		})
+	}
+}
+
+func TestFakeCloseNotification(t *testing.T) {
+	fake := notificationstest.NewFake()
+	var closed []notifications.CloseReason
+	notifier, err := notificationstest.NewNotifier(fake, notifications.WithOptions(func(o *notifications.Options) {
+		o.OnClosed = func(id uint32, reason notifications.CloseReason) { closed = append(closed, reason) }
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer notifier.Close()
+
+	notifier.Notify(&notifications.TrackInfo{Title: "One"}, notifications.StatePlaying)
+	first, _ := fake.Last()
+	fake.CloseNotification(first.ID, notifications.CloseDismissed)
+	if len(closed) != 1 || closed[0] != notifications.CloseDismissed {
+		t.Errorf("OnClosed got %v, want [%v]", closed, notifications.CloseDismissed)
+	}
+
+	// A closed notification isn't replaced
+	notifier.Notify(&notifications.TrackInfo{Title: "Two"}, notifications.StatePlaying)
+	if second, _ := fake.Last(); second.ReplacesID != 0 {
+		t.Errorf("replaced closed notification %d", second.ReplacesID)
+	}
+}
+
+func TestFakeDismissed(t *testing.T) {
+	fake := notificationstest.NewFake()
+	notifier, err := notificationstest.NewNotifier(fake)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer notifier.Close()
+
+	notifier.Notify(&notifications.TrackInfo{Title: "Song"}, notifications.StatePlaying)
+	last, _ := fake.Last()
+	notifier.Clear()
+	if dismissed := fake.Dismissed(); len(dismissed) != 1 || dismissed[0] != last.ID {
+		t.Errorf("dismissed %v, want [%d]", dismissed, last.ID)
+	}
+
+	fake.Reset()
+	fake.AssertNone(t)
+}
+
+func TestNewNotifierKeepsOptions(t *testing.T) {
+	opts := make([]notifications.Option, 1, 2)
+	opts[0] = notifications.WithUrgency(notifications.UrgencyLow)
+	spare := opts[:2]
+	spare[1] = nil
+
+	notifier, err := notificationstest.NewNotifier(notificationstest.NewFake(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer notifier.Close()
+	if spare[1] != nil {
+		t.Error("NewNotifier wrote into the options' backing array")
+	}
+}
+
+func TestNewNotifierConcurrent(t *testing.T) {
+	const notifiers = 8
+	fakes := make([]*notificationstest.Fake, notifiers)
+	var wg sync.WaitGroup
+	for i := range fakes {
+		fakes[i] = notificationstest.NewFake()
+		wg.Add(1)
+		go func(fake *notificationstest.Fake) {
+			defer wg.Done()
+			notifier, err := notificationstest.NewNotifier(fake)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer notifier.Close()
+			notifier.Notify(&notifications.TrackInfo{Title: "Song"}, notifications.StatePlaying)
+		}(fakes[i])
+	}
+	wg.Wait()
+
+	for _, fake := range fakes {
+		fake.AssertCount(t, 1)
+	}
+}
@@ -0,0 +1,177 @@
+// Package notificationstest provides backends for testing code that uses
+// notifications, without a notification daemon
+package notificationstest
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/go-music-players/notifications"
+)
+
+// ErrInjected is returned by sends that Faults made fail
+var ErrInjected = errors.New("notificationstest: injected failure")
+
+// Faults configures the failures a FaultyBackend injects
+type Faults struct {
+	FailEvery int     // Fail every Nth send (0 for never)
+	FailRate  float64 // Probability of failing any other send, 0 to 1
+
+	// Err is the error failed sends return. Set it to one wrapping
+	// notifications.ErrTimeout to have the notifier retry them as it
+	// would a busy daemon's. (default: ErrInjected)
+	Err error
+
+	Delay  time.Duration // Added to every send
+	Jitter time.Duration // Up to this much more, at random
+
+	// MalformedIDs returns IDs a real daemon never would: alternately 0
+	// and the ID of the previous notification
+	MalformedIDs bool
+
+	Seed int64 // Seed for FailRate and Jitter, for reproducible runs
+}
+
+// FaultyBackend is a notifications.Backend that fails, stalls and returns
+// bad IDs as configured. It delivers to a wrapped backend, or nowhere.
+type FaultyBackend struct {
+	inner notifications.Backend // nil to deliver nowhere
+
+	mu       sync.Mutex
+	faults   Faults
+	rand     *rand.Rand
+	sends    int                           // Sends attempted
+	failures int                           // Sends failed on purpose
+	sent     []*notifications.Notification // Sends that succeeded
+	lastID   uint32                        // Real ID of the last delivered notification
+	closed   bool
+}
+
+// NewFaultyBackend creates a backend injecting faults and otherwise
+// accepting every notification
+func NewFaultyBackend(faults Faults) *FaultyBackend {
+	return Wrap(nil, faults)
+}
+
+// Wrap injects faults into inner, which receives the notifications that
+// aren't failed
+func Wrap(inner notifications.Backend, faults Faults) *FaultyBackend {
+	return &FaultyBackend{
+		inner:  inner,
+		faults: faults,
+		rand:   rand.New(rand.NewSource(faults.Seed)),
+	}
+}
+
+// Register makes backend available to notifications.NewNotifier under
+// name. Every notifier opened with the name shares it.
+func Register(name string, backend notifications.Backend) {
+	notifications.Register(name, func(notifications.BackendConfig) (notifications.Backend, error) {
+		return backend, nil
+	})
+}
+
+// SetFaults changes the faults injected from the next send on, e.g. to
+// let a daemon "recover"
+func (b *FaultyBackend) SetFaults(faults Faults) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.faults = faults
+}
+
+// Send delays, then fails or delivers the notification
+func (b *FaultyBackend) Send(note *notifications.Notification) (uint32, error) {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return 0, fmt.Errorf("notificationstest: backend closed")
+	}
+	b.sends++
+	faults := b.faults
+	delay := faults.Delay
+	if faults.Jitter > 0 {
+		delay += time.Duration(b.rand.Int63n(int64(faults.Jitter)))
+	}
+	fail := faults.FailEvery > 0 && b.sends%faults.FailEvery == 0 ||
+		faults.FailRate > 0 && b.rand.Float64() < faults.FailRate
+	if fail {
+		b.failures++
+	}
+	b.mu.Unlock()
+
+	time.Sleep(delay)
+	if fail {
+		if faults.Err != nil {
+			return 0, faults.Err
+		}
+		return 0, ErrInjected
+	}
+
+	var id uint32
+	if b.inner != nil {
+		var err error
+		if id, err = b.inner.Send(note); err != nil {
+			return 0, err
+		}
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sent = append(b.sent, note)
+	if b.inner == nil {
+		id = uint32(len(b.sent))
+	}
+	previous := b.lastID
+	b.lastID = id
+	if faults.MalformedIDs {
+		if len(b.sent)%2 == 1 {
+			return 0, nil
+		}
+		return previous, nil
+	}
+	return id, nil
+}
+
+// Capabilities returns the wrapped backend's, or replacement and actions
+func (b *FaultyBackend) Capabilities() notifications.Capabilities {
+	if b.inner != nil {
+		return b.inner.Capabilities()
+	}
+	return notifications.Capabilities{Actions: true, Replacement: true}
+}
+
+// Close closes the wrapped backend; later sends fail
+func (b *FaultyBackend) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+
+	if b.inner != nil {
+		return b.inner.Close()
+	}
+	return nil
+}
+
+// Sends returns how many sends were attempted
+func (b *FaultyBackend) Sends() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.sends
+}
+
+// Failures returns how many sends were failed on purpose
+func (b *FaultyBackend) Failures() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.failures
+}
+
+// Sent returns the notifications that were delivered, in order
+func (b *FaultyBackend) Sent() []*notifications.Notification {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]*notifications.Notification{}, b.sent...)
+}
@@ -0,0 +1,127 @@
+package notificationstest_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-music-players/notifications"
+	"github.com/go-music-players/notifications/notificationstest"
+)
+
+// errBusy is a transient failure, as a busy daemon's timeout
+var errBusy = fmt.Errorf("daemon busy: %w", notifications.ErrTimeout)
+
+func TestFaultyBackendRetry(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		faults      notificationstest.Faults
+		maxAttempts int
+		warmUp      bool // Notify a track first, using up a send
+		wantErr     error
+		wantSends   int
+		wantSent    int
+	}{
+		{
+			name:        "transient failure retried",
+			faults:      notificationstest.Faults{FailEvery: 1, Err: errBusy},
+			maxAttempts: 3,
+			wantErr:     notifications.ErrTimeout,
+			wantSends:   3,
+		},
+		{
+			name:        "retries disabled",
+			faults:      notificationstest.Faults{FailEvery: 1, Err: errBusy},
+			maxAttempts: 0,
+			wantErr:     notifications.ErrTimeout,
+			wantSends:   1,
+		},
+		{
+			name:        "other errors not retried",
+			faults:      notificationstest.Faults{FailEvery: 1},
+			maxAttempts: 3,
+			wantErr:     notificationstest.ErrInjected,
+			wantSends:   1,
+		},
+		{
+			name:        "recovers on retry",
+			faults:      notificationstest.Faults{FailEvery: 2, Err: errBusy},
+			maxAttempts: 3,
+			warmUp:      true,
+			wantSends:   3,
+			wantSent:    2,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := notificationstest.NewFaultyBackend(tt.faults)
+			name := "faulty-" + t.Name()
+			notificationstest.Register(name, backend)
+			notifier, err := notifications.NewNotifier(
+				notifications.WithBackend(name),
+				notifications.WithOptions(func(o *notifications.Options) {
+					o.Retry = notifications.RetryPolicy{MaxAttempts: tt.maxAttempts, Backoff: time.Millisecond}
+				}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer notifier.Close()
+
+			if tt.warmUp {
+				notifier.Notify(&notifications.TrackInfo{Title: "Warm-up"}, notifications.StatePlaying)
+			}
+
+			err = notifier.Notify(&notifications.TrackInfo{Title: "Song"}, notifications.StatePlaying)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Notify() = %v, want %v", err, tt.wantErr)
+			}
+			if sends := backend.Sends(); sends != tt.wantSends {
+				t.Errorf("%d sends, want %d", sends, tt.wantSends)
+			}
+			if sent := len(backend.Sent()); sent != tt.wantSent {
+				t.Errorf("%d delivered, want %d", sent, tt.wantSent)
+			}
+		})
+	}
+}
+
+func TestFaultyBackendRetryGivesUpWhenStale(t *testing.T) {
+	backend := notificationstest.NewFaultyBackend(notificationstest.Faults{FailEvery: 1, Err: errBusy})
+	notificationstest.Register("faulty-stale", backend)
+	notifier, err := notifications.NewNotifier(
+		notifications.WithBackend("faulty-stale"),
+		notifications.WithOptions(func(o *notifications.Options) {
+			o.StaleAfter = 50 * time.Millisecond
+			o.Retry = notifications.RetryPolicy{MaxAttempts: 10, Backoff: 40 * time.Millisecond}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer notifier.Close()
+
+	notifier.Notify(&notifications.TrackInfo{Title: "Song"}, notifications.StatePlaying)
+	if sends := backend.Sends(); sends != 2 {
+		t.Errorf("%d sends, want 2 before going stale", sends)
+	}
+}
+
+func TestFaultyBackendMalformedIDs(t *testing.T) {
+	fake := notificationstest.NewFake()
+	backend := notificationstest.Wrap(fake, notificationstest.Faults{MalformedIDs: true})
+	notificationstest.Register("faulty-malformed", backend)
+	notifier, err := notifications.NewNotifier(notifications.WithBackend("faulty-malformed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer notifier.Close()
+
+	for _, title := range []string{"One", "Two", "Three"} {
+		if err := notifier.Notify(&notifications.TrackInfo{Title: title}, notifications.StatePlaying); err != nil {
+			t.Errorf("Notify(%s) = %v", title, err)
+		}
+	}
+	fake.AssertCount(t, 3)
+	fake.AssertLast(t, "Three", "Now Playing")
+}
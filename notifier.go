@@ -0,0 +1,1366 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Notifier sends desktop notifications through a Backend. It is safe for
+// concurrent use, e.g. by a player's event loop and a "show now playing"
+// hotkey. A nil Notifier (as returned when NewNotifier fails) is safe to
+// use; its methods do nothing.
+type Notifier struct {
+	backend     Backend
+	backendName string
+	art         *artLoader   // Loads and caches album art
+	coord       *coordinator // Other instances, for Options.Coordinate (nil for none)
+
+	// Power profile for Options.PowerSaving, only watched from the first
+	// time it is consulted
+	powerOnce sync.Once
+	power     *powerProfile
+
+	// callMu serializes the methods that change the state below, up to
+	// the mu-guarded fields. Callbacks run while it is held.
+	callMu sync.Mutex
+
+	// Settings in effect, replaced whole by Reconfigure. configMu
+	// serializes reconfiguration; base is the settings before templates
+	// were compiled into them.
+	options  atomic.Pointer[Options]
+	configMu sync.Mutex
+	base     Options
+
+	closed atomic.Bool // Set by Close; the backend is unusable after
+
+	lastIDs        map[string]string        // Track ID to detect changes, by dedup scope
+	sourceStates   map[string]PlaybackState // Last reported state of each source
+	lastStations   map[string]string        // Station to detect station changes, by dedup scope
+	snoozedUntil   time.Time                // Notifications are muted until this time
+	blockedArtists map[string]string        // Artists muted with BlockArtist, by lowercase name
+	state          PlaybackState            // Current playback state
+	stateSince     time.Time                // When the current state began
+	positionKey    string                   // Track lastPosition belongs to
+	lastPosition   time.Duration            // Last reported playback position
+
+	lastLoudness    float64 // Loudness of the previous track in LUFS
+	hasLastLoudness bool    // Whether lastLoudness is known
+
+	// Guarded by mu, since live updates spend it from their ticker
+	budgetKey  string // Track the budget is being spent on
+	budgetUsed int    // Notifications shown for budgetKey
+
+	album      albumSquash // Tracklist for SquashAlbums
+	chapterKey string      // Chapter last notified, for NotifyOnChapter
+
+	sampleCount int    // Track changes seen by the sampling policy
+	listenedKey string // Track the last EventListened was emitted for
+
+	errorKey   string    // Last error shown by NotifyError
+	errorCount int       // Occurrences of errorKey
+	errorAt    time.Time // When errorKey last occurred
+	errorID    uint32    // Notification showing errorKey
+
+	iconCache  map[string]bool   // Whether icon names could be found
+	rasterized map[string]string // PNG renderings of SVG icons by path
+
+	liveMu sync.Mutex
+	live   *liveUpdates // Running live updates (nil for none)
+
+	// Guarded by mu, since backends update them from their own goroutines
+	// when notifications are closed, and live updates are sent from theirs
+	mu           sync.Mutex
+	shown        map[uint32]*sentNotification // Notifications we created that may still be open
+	replaceID    uint32                       // Replace previous notification
+	current      uint32                       // Most recent track notification, for Clear
+	last         *sentNotification            // Most recently shown notification, for in-place edits
+	lastDelivery DeliveryReport               // Outcome of the most recent attempt
+	stats        Stats                        // Outcome counts
+	statsByDay   []Stats                      // Outcome counts of the last statsDays days, oldest first
+	raiser       func(track *TrackInfo)       // Raises the player when OnRaise is nil
+	queue        []queuedNotification         // Held for a remote backend while offline
+	replaying    bool                         // Whether replayLoop is running
+}
+
+// sentNotification is a delivered notification, kept so it can be edited
+// and so invoked actions can be routed back to their handlers
+type sentNotification struct {
+	appName string
+	icon    string
+	payload Payload
+	track   *TrackInfo        // Track the notification is about (nil for messages)
+	state   PlaybackState     // Playback state shown (empty for messages)
+	actions map[string]Action // Actions by ID
+	id      uint32
+	sent    time.Time // When it was last shown, for pruning Notifier.shown
+	event   Event     // Only EventStarted notifications become the replace target
+	key     string    // Track key, for the per-track budget
+
+	// When the notification becomes stale and is dropped instead of sent
+	// (zero for never)
+	deadline time.Time
+
+	live       bool   // Kept resident and refreshed by live updates
+	timeout    *int32 // Overrides Options.Timeout (nil for none)
+	standalone bool   // Doesn't become the notification the next one replaces
+	transient  bool   // Kept out of the notification history regardless of Options.Transient
+
+	ctx context.Context // Trace context of the call sending it (nil for none)
+}
+
+const (
+	// restartMinPlayed is how far into a track playback must have been for
+	// a jump back to count as a restart rather than a seek near the start
+	restartMinPlayed = 10 * time.Second
+
+	// restartWindow is how close to the start a restarted track must be
+	restartWindow = 3 * time.Second
+
+	// errorCollapseWindow is how long after an error a repeat of it is
+	// counted on the same notification
+	errorCollapseWindow = 5 * time.Minute
+
+	// maxShown caps the notifications remembered as open. Backends that
+	// never report closes would otherwise grow the list forever.
+	maxShown = 100
+)
+
+// NewNotifier creates a notifier using Options.Backend, or the platform's
+// default backend. If it can't be opened, the fallback backends are tried
+// in order. Without an Options struct among the options, settings start
+// from DefaultOptions.
+func NewNotifier(opts ...Option) (*Notifier, error) {
+	options := newOptions(opts)
+	name := options.Backend
+	fallbacks := options.FallbackBackends
+	if name == "" {
+		name = defaultBackend
+		if fallbacks == nil {
+			fallbacks = defaultFallbacks
+		}
+	}
+
+	n := &Notifier{
+		replaceID:      0,
+		shown:          make(map[uint32]*sentNotification),
+		lastIDs:        make(map[string]string),
+		lastStations:   make(map[string]string),
+		sourceStates:   make(map[string]PlaybackState),
+		blockedArtists: make(map[string]string),
+		stats:          Stats{Since: time.Now()},
+		art:            newArtLoader(options),
+	}
+	n.art.skipDownloads = func() bool { return n.powerSaving().SkipArtDownloads }
+
+	if err := n.configure(options); err != nil {
+		return nil, err
+	}
+
+	config := BackendConfig{
+		Options:  options,
+		OnAction: n.actionInvoked,
+		OnClosed: n.notificationClosed,
+	}
+
+	log := options.logger()
+	var errs []error
+	for _, candidate := range append([]string{name}, fallbacks...) {
+		backend, err := openBackend(candidate, config)
+		if err != nil {
+			log.Debug("notification backend unavailable", "backend", candidate, "err", err)
+			errs = append(errs, err)
+			continue
+		}
+		log.Debug("notification backend opened", "backend", candidate, "capabilities", backend.Capabilities())
+		n.backend = backend
+		n.backendName = candidate
+		if options.Coordinate {
+			n.coord = coordinate()
+		}
+		n.loadState()
+		n.loadStats()
+		n.loadBlockedArtists()
+		n.loadQueue()
+		return n, nil
+	}
+
+	if len(errs) == 1 {
+		return nil, errs[0]
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Primary reports whether this instance shows track notifications, rather
+// than deferring to another one with Options.Coordinate
+func (n *Notifier) Primary() bool {
+	if n == nil {
+		return false
+	}
+	return n.coord.active()
+}
+
+// Backend returns the name of the backend in use, which differs from
+// Options.Backend when a fallback was used
+func (n *Notifier) Backend() string {
+	if n == nil {
+		return ""
+	}
+	return n.backendName
+}
+
+// Close closes the backend
+func (n *Notifier) Close() error {
+	if n == nil || n.backend == nil || n.closed.Swap(true) {
+		return nil
+	}
+	n.StopLiveUpdates()
+	err := n.saveStats()
+	n.art.close()
+	n.powerOnce.Do(func() {}) // Don't start watching while closing
+	n.power.close()
+	n.coord.close()
+	if closeErr := n.backend.Close(); closeErr != nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Notify shows a notification for a track
+// Only notifies if the track has changed (based on title/artist/album)
+func (n *Notifier) Notify(track *TrackInfo, state PlaybackState) error {
+	return n.NotifyWithOptions(track, state)
+}
+
+// NotifyWithOptions is Notify with settings overridden for this
+// notification only
+func (n *Notifier) NotifyWithOptions(track *TrackInfo, state PlaybackState, opts ...NotifyOption) error {
+	if n == nil {
+		return nil
+	}
+	n.callMu.Lock()
+	defer n.callMu.Unlock()
+
+	if state == StateStopped && n.opts().ClearOnStop {
+		return n.stopped(track)
+	}
+	if track == nil {
+		return n.suppress(track, SuppressNoTrack)
+	}
+	track = track.withSource()
+
+	// Every report counts towards source priority, including suppressed
+	// ones, so a paused player stops outranking the others
+	if track.Source != "" {
+		n.sourceStates[track.Source] = state
+	}
+
+	// Options passed for this call override the rules
+	var call callOptions
+	ruled := applyRules(n.opts().Rules, track, state, &call)
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&call)
+		}
+	}
+
+	// Don't notify if nothing is playing
+	if track.Title == "" && track.Artist == "" {
+		return n.suppress(track, SuppressNoTrack)
+	}
+
+	pausedFor := n.transition(state)
+
+	// Completions are independent of whether a popup would be shown
+	if err := n.checkListened(track); err != nil {
+		return err
+	}
+
+	if ruled {
+		return n.suppress(track, SuppressRule)
+	}
+	if n.blocked(track) {
+		return n.suppress(track, SuppressBlockedArtist)
+	}
+
+	// Don't notify on pause unless configured to do so
+	if state == StatePaused && !n.opts().NotifyOnPause {
+		return n.suppress(track, SuppressPaused)
+	}
+
+	// Muted for a while via Snooze
+	if time.Now().Before(n.snoozedUntil) {
+		return n.suppress(track, SuppressSnoozed)
+	}
+
+	if n.doNotDisturb() {
+		return n.suppress(track, SuppressDoNotDisturb)
+	}
+
+	// Don't leak the playlist while presenting or sharing the screen
+	if n.opts().SuppressDuringPresentation && n.presentationActive() {
+		return n.suppress(track, SuppressPresentation)
+	}
+
+	// A lower-priority player doesn't interrupt a higher-priority one
+	if n.opts().DedupScope == DedupGlobal && n.outranked(track.Source) {
+		return n.suppress(track, SuppressLowerPriority)
+	}
+
+	scope := ""
+	if n.opts().DedupScope == DedupPerSource {
+		scope = track.Source
+	}
+
+	// Switching stations always notifies, even if the stream metadata
+	// hasn't changed yet
+	stationChanged := track.Station != "" && track.Station != n.lastStations[scope]
+	n.lastStations[scope] = track.Station
+
+	// Check if track has changed
+	currentID := trackKey(track)
+	resumed := n.opts().ResumeAfter > 0 && pausedFor >= n.opts().ResumeAfter
+	restarted := n.restarted(currentID, track.Position)
+	if currentID == n.lastIDs[scope] && !stationChanged && !resumed && !restarted {
+		if n.chapterChanged(currentID, track) {
+			if !n.coord.active() {
+				return n.suppress(track, SuppressDeferred)
+			}
+			return n.show(n.opts().chapterRenderer(n.backend.Capabilities()), track, state, call)
+		}
+		return n.suppress(track, SuppressSameTrack) // Same track, don't notify again
+	}
+
+	// Sampling only thins out new tracks, not resumes or restarts
+	if currentID != n.lastIDs[scope] {
+		if !n.playedEnough(track) {
+			// Not remembered, so a later update past the threshold notifies
+			return n.suppress(track, SuppressSampled)
+		}
+		if !n.sampleTrackChange() {
+			n.lastIDs[scope] = currentID
+			return n.suppress(track, SuppressSampled)
+		}
+	}
+
+	// Update last track; the track notification covers its chapter
+	n.lastIDs[scope] = currentID
+	n.chapterKey = chapterKey(currentID, track)
+
+	// Tracks are remembered even while deferring, so taking over from
+	// another instance doesn't repeat the track it just showed
+	if !n.coord.active() {
+		return n.suppress(track, SuppressDeferred)
+	}
+
+	// Show notification
+	caps := n.backend.Capabilities()
+	renderer := n.opts().renderer(caps)
+	if stationChanged {
+		renderer = n.opts().stationRenderer(caps)
+	}
+	if n.opts().SquashAlbums {
+		renderer = n.squashAlbum(renderer, track)
+	}
+	if resumed {
+		renderer = withBodyLine(renderer, "Resumed after "+formatPause(pausedFor))
+	}
+	if restarted {
+		renderer = withSummaryPrefix(renderer, "Restarted: ")
+	}
+	if err := n.show(renderer, track, state, call); err != nil {
+		return err
+	}
+	if err := n.saveState(); err != nil {
+		return err
+	}
+
+	return n.checkLoudness(track)
+}
+
+// restarted reports whether the same track jumped back to its beginning.
+// Players that don't report a position never trigger it.
+func (n *Notifier) restarted(key string, position time.Duration) bool {
+	previousKey, previous := n.positionKey, n.lastPosition
+	n.positionKey, n.lastPosition = key, position
+
+	return n.opts().NotifyOnRestart &&
+		key == previousKey &&
+		previous >= restartMinPlayed &&
+		position < restartWindow
+}
+
+// playedEnough reports whether enough of the track has played for
+// SamplingPolicy.MinPlayed
+func (n *Notifier) playedEnough(track *TrackInfo) bool {
+	minPlayed := n.opts().Sampling.MinPlayed
+	if minPlayed <= 0 {
+		return true
+	}
+	if track.Duration <= 0 {
+		return false
+	}
+	return float64(track.Position)/float64(track.Duration) >= minPlayed
+}
+
+// sampleTrackChange counts a track change and reports whether it is the
+// 1 in SamplingPolicy.EveryN that gets posted
+func (n *Notifier) sampleTrackChange() bool {
+	every := n.opts().Sampling.EveryN
+	if every <= 1 {
+		return true
+	}
+	posted := n.sampleCount%every == 0
+	n.sampleCount++
+	return posted
+}
+
+// transition records the playback state and, when playback resumes from
+// pause, returns how long it was paused
+func (n *Notifier) transition(state PlaybackState) time.Duration {
+	if state == n.state {
+		return 0
+	}
+
+	now := time.Now()
+	previous, since := n.state, n.stateSince
+	n.state, n.stateSince = state, now
+
+	if previous == StatePaused && state == StatePlaying && !since.IsZero() {
+		return now.Sub(since)
+	}
+	return 0
+}
+
+// outranked reports whether another source with a higher SourcePriority
+// is currently playing
+func (n *Notifier) outranked(source string) bool {
+	if len(n.opts().SourcePriority) == 0 {
+		return false
+	}
+
+	rank := sourceRank(n.opts().SourcePriority, source)
+	for other, state := range n.sourceStates {
+		if other != source && state == StatePlaying && sourceRank(n.opts().SourcePriority, other) < rank {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceRank returns a source's position in the priority list; unlisted
+// sources rank below all listed ones
+func sourceRank(priority []string, source string) int {
+	for i, s := range priority {
+		if s == source || s == sourcePlayer(source) {
+			return i
+		}
+	}
+	return len(priority)
+}
+
+// checkLoudness warns when a track is much louder than the previous one
+func (n *Notifier) checkLoudness(track *TrackInfo) error {
+	loudness, ok := track.loudness()
+	previous, hadPrevious := n.lastLoudness, n.hasLastLoudness
+	n.lastLoudness, n.hasLastLoudness = loudness, ok
+
+	if n.opts().LoudnessWarning <= 0 || !ok || !hadPrevious {
+		return nil
+	}
+
+	jump := loudness - previous
+	if jump < n.opts().LoudnessWarning {
+		return nil
+	}
+
+	return n.showMessage(Payload{
+		Summary: "Loudness jump",
+		Body:    fmt.Sprintf("%s is %.1f LU louder than the previous track", track.Title, jump),
+		Icon:    "dialog-warning",
+	})
+}
+
+// suppress reports a skipped notification to OnSuppressed.
+// Suppression is not an error, so it always returns nil.
+func (n *Notifier) suppress(track *TrackInfo, reason SuppressionReason) error {
+	if track != nil {
+		n.opts().logger().Debug("notification suppressed", "reason", reason, "title", track.Title, "artist", track.Artist, "source", track.Source)
+	} else {
+		n.opts().logger().Debug("notification suppressed", "reason", reason)
+	}
+	if n.opts().OnSuppressed != nil {
+		n.opts().OnSuppressed(track, reason)
+	}
+	n.report(DeliveryReport{Status: DeliverySuppressed, Reason: reason})
+	return nil
+}
+
+// report records the outcome of a notification attempt
+func (n *Notifier) report(r DeliveryReport) {
+	r.Backend = n.backendName
+	r.Time = time.Now()
+	n.mu.Lock()
+	n.lastDelivery = r
+	n.stats.record(r)
+	var day *Stats
+	n.statsByDay, day = today(n.statsByDay, r.Time)
+	day.record(r)
+	n.mu.Unlock()
+	if n.opts().Metrics != nil {
+		observe(n.opts().Metrics, r)
+	}
+	if n.opts().OnDelivery != nil {
+		n.opts().OnDelivery(r)
+	}
+}
+
+// LastDelivery returns the outcome of the most recent notification attempt
+func (n *Notifier) LastDelivery() DeliveryReport {
+	if n == nil {
+		return DeliveryReport{}
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.lastDelivery
+}
+
+// Stats returns how many notifications were delivered, failed and were
+// suppressed (by reason), to help tune filters and suppression options
+func (n *Notifier) Stats() Stats {
+	if n == nil {
+		return Stats{}
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.stats.clone()
+}
+
+// StatsByDay returns the counts of Stats for each of the last 30 days
+// with any notifications, oldest first. Each day's Since is its midnight.
+func (n *Notifier) StatsByDay() []Stats {
+	if n == nil {
+		return nil
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	days := make([]Stats, len(n.statsByDay))
+	for i, day := range n.statsByDay {
+		days[i] = day.clone()
+	}
+	return days
+}
+
+// ResetStats starts counting over
+func (n *Notifier) ResetStats() error {
+	if n == nil {
+		return nil
+	}
+	n.mu.Lock()
+	n.stats = Stats{Since: time.Now()}
+	n.statsByDay = nil
+	n.mu.Unlock()
+	return n.saveStats()
+}
+
+// NotifyNow shows a notification immediately without deduplication
+func (n *Notifier) NotifyNow(track *TrackInfo, state PlaybackState) error {
+	if n == nil || track == nil {
+		return nil
+	}
+	n.callMu.Lock()
+	defer n.callMu.Unlock()
+
+	return n.showNotification(track.withSource(), state)
+}
+
+// SendTest shows a sample notification through the configured pipeline
+// (enrichers, renderer, actions, album art), for "Test notification"
+// buttons in settings screens. It doesn't affect deduplication, the
+// per-track budget, live updates or what the next track replaces.
+func (n *Notifier) SendTest() error {
+	if n == nil {
+		return nil
+	}
+	n.callMu.Lock()
+	defer n.callMu.Unlock()
+
+	track := sampleTrack()
+	if path, err := sampleArt(); err == nil {
+		track.ImageURL = path
+	}
+
+	// Edits keep going to the last real notification
+	n.mu.Lock()
+	last := n.last
+	n.mu.Unlock()
+	defer func() {
+		n.mu.Lock()
+		n.last = last
+		n.mu.Unlock()
+	}()
+
+	return n.show(n.opts().renderer(n.backend.Capabilities()), track, StatePlaying, callOptions{sample: true})
+}
+
+// showNotification displays a desktop notification using the default renderer
+func (n *Notifier) showNotification(track *TrackInfo, state PlaybackState) error {
+	return n.show(n.opts().renderer(n.backend.Capabilities()), track, state, callOptions{})
+}
+
+// show renders and displays a desktop notification
+func (n *Notifier) show(renderer Renderer, track *TrackInfo, state PlaybackState, call callOptions) (err error) {
+	ctx := call.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := n.trace(ctx, "notifications.show")
+	defer func() { span.End(err) }()
+
+	deadline := n.deadline()
+	if !n.subscribes(EventStarted) {
+		return n.suppress(track, SuppressUnsubscribed)
+	}
+	if n.opts().ShowOrigin && track.Origin != (Origin{}) {
+		renderer = withBodyLine(renderer, "via "+track.Origin.String())
+	}
+
+	key := trackKey(track)
+	if !call.sample && !n.spendBudget(key) {
+		return n.suppress(track, SuppressBudget)
+	}
+
+	track = enrich(n.opts().Enrichers, n.opts().EnrichBudget, track)
+
+	_, renderSpan := n.trace(ctx, "notifications.render")
+	payload, err := renderer.Render(track, state)
+	renderSpan.End(err)
+	if err != nil {
+		return fmt.Errorf("failed to render notification: %w", err)
+	}
+
+	caps := n.backend.Capabilities()
+	payload.Actions = n.actions(payload.Actions, caps, state)
+	payload = payload.degrade(caps)
+	if payload.Urgency == UrgencyNormal {
+		payload.Urgency = n.opts().Urgency
+	}
+	if call.urgency != nil {
+		payload.Urgency = *call.urgency
+	}
+
+	appName, icon := n.identity(track.Source)
+	if payload.Icon != "" {
+		icon = n.resolveIcon(payload.Icon)
+	}
+	if call.icon != "" {
+		icon = n.resolveIcon(call.icon)
+	}
+
+	// Determine replace ID
+	n.mu.Lock()
+	replaceID := n.replaceID
+	if call.replace != nil && *call.replace {
+		replaceID = n.current
+	}
+	n.mu.Unlock()
+	standalone := call.sample || call.replace != nil && !*call.replace
+	if standalone || !n.opts().ReplaceExisting && call.replace == nil {
+		replaceID = 0 // Always create new notification
+	}
+
+	// The new track takes over from any live updates
+	if !call.sample {
+		n.stopLiveLocked()
+	}
+
+	return n.send(&sentNotification{
+		appName:    appName,
+		icon:       icon,
+		payload:    payload,
+		track:      track,
+		state:      state,
+		key:        key,
+		deadline:   deadline,
+		timeout:    call.timeout,
+		standalone: standalone,
+		ctx:        ctx,
+	}, replaceID)
+}
+
+// checkListened emits an EventListened once a track has played past
+// Options.ListenedAt
+func (n *Notifier) checkListened(track *TrackInfo) error {
+	if n.opts().ListenedAt <= 0 || track.Duration <= 0 {
+		return nil
+	}
+	key := trackKey(track)
+	if key == n.listenedKey {
+		return nil // Already emitted for this track
+	}
+	if float64(track.Position)/float64(track.Duration) < n.opts().ListenedAt {
+		return nil
+	}
+	n.listenedKey = key
+
+	if !n.subscribes(EventListened) {
+		return nil
+	}
+	deadline := n.deadline()
+
+	track = enrich(n.opts().Enrichers, n.opts().EnrichBudget, track)
+	caps := n.backend.Capabilities()
+	payload, err := n.opts().renderer(caps).Render(track, StatePlaying)
+	if err != nil {
+		return fmt.Errorf("failed to render notification: %w", err)
+	}
+	payload = payload.degrade(caps)
+	if payload.Urgency == UrgencyNormal {
+		payload.Urgency = n.opts().Urgency
+	}
+
+	appName, icon := n.identity(track.Source)
+	if payload.Icon != "" {
+		icon = n.resolveIcon(payload.Icon)
+	}
+	return n.send(&sentNotification{
+		appName:  appName,
+		icon:     icon,
+		payload:  payload,
+		track:    track,
+		state:    StatePlaying,
+		event:    EventListened,
+		key:      key,
+		deadline: deadline,
+	}, 0)
+}
+
+// deadline returns when a notification started now becomes stale
+func (n *Notifier) deadline() time.Time {
+	if n.opts().StaleAfter <= 0 {
+		return time.Time{}
+	}
+	// Drop the monotonic reading: the monotonic clock stops while the
+	// machine is suspended, which is exactly the delay to catch
+	return time.Now().Round(0).Add(n.opts().StaleAfter)
+}
+
+// subscribes reports whether the backend wants an event
+func (n *Notifier) subscribes(event Event) bool {
+	if subscriber, ok := n.backend.(EventSubscriber); ok {
+		return subscriber.Subscribes(event)
+	}
+	return event != EventListened
+}
+
+// spendBudget counts a notification against the per-track budget and
+// reports whether it may be shown
+func (n *Notifier) spendBudget(key string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if key != n.budgetKey {
+		n.budgetKey = key
+		n.budgetUsed = 0
+	}
+	if n.opts().MaxPerTrack > 0 && n.budgetUsed >= n.opts().MaxPerTrack {
+		return false
+	}
+	n.budgetUsed++
+	return true
+}
+
+// identity returns the app name and icon to show for a source
+func (n *Notifier) identity(source string) (appName, icon string) {
+	// Application name
+	appName = n.opts().AppName
+	icon = n.opts().Icon
+
+	// Per-source identity overrides the global one
+	identity, ok := n.opts().SourceApps[source]
+	if !ok {
+		identity, ok = n.opts().SourceApps[sourcePlayer(source)]
+	}
+	if ok && source != "" {
+		if identity.AppName != "" {
+			appName = identity.AppName
+		}
+		if identity.Icon != "" {
+			icon = identity.Icon
+		}
+	}
+
+	if appName == "" {
+		appName = "Music Player"
+	}
+
+	// Icon
+	if icon == "" {
+		icon = "media-playback-start"
+	}
+
+	return appName, n.resolveIcon(icon)
+}
+
+// showMessage displays a notification that isn't about a track, such as a
+// confirmation. It never replaces the current track notification.
+func (n *Notifier) showMessage(payload Payload) error {
+	return n.send(n.message(payload), 0)
+}
+
+// message builds a notification that isn't about a track
+func (n *Notifier) message(payload Payload) *sentNotification {
+	appName, icon := n.identity("")
+	if payload.Icon != "" {
+		icon = n.resolveIcon(payload.Icon)
+	}
+	return &sentNotification{
+		appName: appName,
+		icon:    icon,
+		payload: payload,
+		event:   EventMessage,
+	}
+}
+
+// NotifyError shows a critical notification about an error, such as a
+// stream failing. Repeats of the same error within errorCollapseWindow
+// update one notification with a counter ("Stream error ×5") instead of
+// stacking popups.
+func (n *Notifier) NotifyError(summary string, err error) error {
+	if n == nil || err == nil {
+		return nil
+	}
+	n.callMu.Lock()
+	defer n.callMu.Unlock()
+
+	if !n.opts().DoNotDisturbAllowErrors && n.doNotDisturb() {
+		return n.suppress(nil, SuppressDoNotDisturb)
+	}
+
+	key := summary + "\n" + err.Error()
+	now := time.Now()
+	if key != n.errorKey || now.Sub(n.errorAt) > errorCollapseWindow {
+		n.errorKey, n.errorCount, n.errorID = key, 0, 0
+	}
+	n.errorCount++
+	n.errorAt = now
+
+	title := summary
+	if n.errorCount > 1 {
+		title = fmt.Sprintf("%s ×%d", summary, n.errorCount)
+	}
+
+	// Update the previous popup while it's open; pop a new one otherwise
+	n.mu.Lock()
+	_, open := n.shown[n.errorID]
+	n.mu.Unlock()
+	replaceID := uint32(0)
+	if open {
+		replaceID = n.errorID
+	}
+
+	note := n.message(Payload{
+		Summary: title,
+		Body:    err.Error(),
+		Icon:    "dialog-error",
+		Urgency: UrgencyCritical,
+	})
+	if err := n.send(note, replaceID); err != nil {
+		return err
+	}
+	n.errorID = note.id
+	return nil
+}
+
+// send delivers a notification, replacing replaceID if it is non-zero
+func (n *Notifier) send(note *sentNotification, replaceID uint32) error {
+	notification := &Notification{
+		AppName:      note.appName,
+		DesktopEntry: n.opts().DesktopEntry,
+		Icon:         note.icon,
+		Summary:      note.payload.Summary,
+		Body:         note.payload.bodyFor(n.backend.Capabilities()),
+		Urgency:      note.payload.Urgency,
+		Actions:      note.payload.Actions,
+		ReplacesID:   replaceID,
+		Timeout:      n.opts().Timeout,
+		Transient:    n.opts().Transient && note.event != EventMessage || note.transient,
+		Progress:     -1,
+		Event:        note.event,
+		Track:        note.track,
+		State:        note.state,
+		Time:         time.Now(),
+	}
+	if note.timeout != nil {
+		notification.Timeout = *note.timeout
+	}
+	if note.live {
+		notification.Timeout = 0
+		notification.Resident = true
+	}
+	if (n.opts().ShowProgress || note.live) && note.track != nil && n.backend.Capabilities().Progress {
+		if progress, ok := note.track.progress(); ok {
+			notification.Progress = progress
+		}
+	}
+	if note.event != EventMessage {
+		notification.SoundName = n.opts().SoundName
+		notification.SoundFile = n.opts().SoundFile
+		notification.SuppressSound = n.opts().SuppressSound
+	}
+	ctx := note.ctx
+	note.ctx = nil // Edits and live updates of it aren't part of the call
+	n.attachArt(ctx, notification, note.payload.ImageURL)
+
+	// Rendering and loading art can stall, e.g. across a suspend
+	if !note.deadline.IsZero() && time.Now().After(note.deadline) {
+		return n.suppress(note.track, SuppressStale)
+	}
+
+	note.actions = make(map[string]Action, len(note.payload.Actions))
+	for _, action := range note.payload.Actions {
+		note.actions[action.ID] = action
+	}
+
+	if n.closed.Load() {
+		return ErrClosed
+	}
+
+	// Remote backends get notifications made while offline once they can
+	// be reached again, in order
+	if n.queues() {
+		if !n.art.online() {
+			n.enqueue(notification)
+			n.report(DeliveryReport{Status: DeliveryQueued})
+			return nil
+		}
+		n.replay()
+	}
+
+	_, span := n.trace(ctx, "notifications.send")
+	start := time.Now()
+	id, err := n.deliver(notification, note.deadline)
+	latency := time.Since(start)
+	span.End(err)
+
+	if err != nil && n.queues() && offline(err) {
+		n.enqueue(notification)
+		n.report(DeliveryReport{Status: DeliveryQueued, Latency: latency, Err: err})
+		return nil
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to show notification: %w", err)
+		n.opts().logger().Debug("notification failed", "backend", n.backendName, "latency", latency, "err", err)
+		n.report(DeliveryReport{Status: DeliveryFailed, Latency: latency, Err: err})
+		return err
+	}
+
+	if id != 0 {
+		note.id = id
+		note.sent = time.Now()
+		n.mu.Lock()
+		n.shown[id] = note
+		n.last = note
+		if len(n.shown) > maxShown {
+			n.pruneShown()
+		}
+
+		// Store the notification ID so we can replace it next time
+		if note.event == EventStarted && !note.standalone {
+			n.current = id
+			if n.opts().ReplaceExisting {
+				n.replaceID = id
+			}
+		}
+		n.mu.Unlock()
+	}
+	n.report(DeliveryReport{Status: DeliveryDelivered, ID: note.id, Latency: latency})
+
+	return nil
+}
+
+// deliver sends a notification through the backend, paced by
+// Options.RemoteRateLimit, retrying transient failures (ErrTimeout or a
+// RateLimitError) as Options.Retry allows. Retries stop once the
+// notification would be stale, or the notifier is closed.
+func (n *Notifier) deliver(notification *Notification, deadline time.Time) (uint32, error) {
+	policy := n.opts().Retry
+	for attempt := 1; ; attempt++ {
+		if err := n.pace(deadline); err != nil {
+			return 0, err
+		}
+		id, err := n.backend.Send(notification)
+		var limited *RateLimitError
+		if errors.As(err, &limited) {
+			n.throttled(limited)
+		}
+		if err == nil || attempt >= policy.MaxAttempts || !errors.Is(err, ErrTimeout) && limited == nil {
+			return id, err
+		}
+		delay := policy.delay(attempt)
+		if limited != nil && limited.After > delay {
+			delay = limited.After
+		}
+		if !deadline.IsZero() && time.Now().Add(delay).After(deadline) {
+			return id, err
+		}
+		n.opts().logger().Debug("retrying notification", "backend", n.backendName, "attempt", attempt, "delay", delay, "err", err)
+		time.Sleep(delay)
+		if n.closed.Load() {
+			return 0, ErrClosed
+		}
+	}
+}
+
+// pruneShown forgets the oldest notification still assumed open, other
+// than the one the next track replaces. Callers must hold n.mu.
+func (n *Notifier) pruneShown() {
+	var oldest *sentNotification
+	for id, note := range n.shown {
+		if id != n.current && id != n.replaceID && (oldest == nil || note.sent.Before(oldest.sent)) {
+			oldest = note
+		}
+	}
+	if oldest != nil {
+		delete(n.shown, oldest.id)
+	}
+}
+
+// attachArt loads album art for a notification. Art that can't be loaded
+// is skipped, so a broken URL never prevents the notification.
+func (n *Notifier) attachArt(ctx context.Context, notification *Notification, imageURL string) {
+	if imageURL == "" {
+		return
+	}
+
+	_, span := n.trace(ctx, "notifications.art")
+	path, img, err := n.art.load(imageURL)
+	span.End(err)
+	if err != nil {
+		return
+	}
+	notification.ImagePath = path
+	notification.Image = img
+}
+
+// actionInvoked routes an invoked action to its handler
+func (n *Notifier) actionInvoked(id uint32, key string) {
+	n.mu.Lock()
+	note := n.shown[id]
+	n.mu.Unlock()
+	if note == nil {
+		return // Not one of ours
+	}
+
+	if action, ok := note.actions[key]; ok && action.Handler != nil {
+		action.Handler(note.track)
+	}
+}
+
+// notificationClosed forgets a closed notification, so the next
+// notification isn't sent as a replacement for a stale ID (which some
+// daemons silently drop)
+func (n *Notifier) notificationClosed(id uint32, reason CloseReason) {
+	ours := n.forget(id)
+	n.liveClosed(id)
+
+	if ours && n.opts().OnClosed != nil {
+		n.opts().OnClosed(id, reason)
+	}
+}
+
+// forget drops a closed notification, reporting whether it was ours
+func (n *Notifier) forget(id uint32) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	_, ours := n.shown[id]
+	delete(n.shown, id)
+	if n.replaceID == id {
+		n.replaceID = 0
+	}
+	if n.current == id {
+		n.current = 0
+	}
+	if n.last != nil && n.last.id == id {
+		n.last = nil
+	}
+	return ours
+}
+
+// doNotDisturb reports whether Do Not Disturb should hold notifications
+// back, for backends that can tell
+func (n *Notifier) doNotDisturb() bool {
+	if !n.opts().RespectDoNotDisturb {
+		return false
+	}
+	detector, ok := n.backend.(DoNotDisturbDetector)
+	return ok && detector.DoNotDisturb()
+}
+
+// presentationActive reports whether the desktop is presenting or sharing
+// the screen, for backends that can tell
+func (n *Notifier) presentationActive() bool {
+	detector, ok := n.backend.(PresentationDetector)
+	return ok && detector.PresentationActive()
+}
+
+// UpdateBody replaces the body of the most recently shown notification in
+// place, e.g. to confirm an action without popping a new notification
+func (n *Notifier) UpdateBody(text string) error {
+	if n == nil {
+		return nil
+	}
+	n.callMu.Lock()
+	defer n.callMu.Unlock()
+
+	last := n.lastShown()
+	if last == nil {
+		return fmt.Errorf("no notification to update")
+	}
+
+	edited := *last
+	edited.payload.Body = text
+	edited.payload.Markup = false
+	return n.sendEdit(&edited)
+}
+
+// AppendLine adds a line to the body of the most recently shown notification
+func (n *Notifier) AppendLine(text string) error {
+	if n == nil {
+		return nil
+	}
+	n.callMu.Lock()
+	defer n.callMu.Unlock()
+
+	last := n.lastShown()
+	if last == nil {
+		return fmt.Errorf("no notification to update")
+	}
+
+	edited := *last
+	if edited.payload.Markup {
+		text = EscapeMarkup(text)
+	}
+	if edited.payload.Body != "" {
+		edited.payload.Body += "\n"
+	}
+	edited.payload.Body += text
+	return n.sendEdit(&edited)
+}
+
+// lastShown returns the most recently shown notification that is still open
+func (n *Notifier) lastShown() *sentNotification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.last
+}
+
+// sendEdit re-sends an edited notification in place of the original
+func (n *Notifier) sendEdit(note *sentNotification) error {
+	note.deadline = time.Time{} // Edits are current by definition
+	if note.event == EventStarted && !n.spendBudget(note.key) {
+		return n.suppress(nil, SuppressBudget)
+	}
+	return n.send(note, note.id)
+}
+
+// resetBudget starts the per-track budget over
+func (n *Notifier) resetBudget() {
+	n.mu.Lock()
+	n.budgetKey, n.budgetUsed = "", 0
+	n.mu.Unlock()
+}
+
+// Snooze mutes track notifications for d and shows a transient
+// confirmation. Notifications resume automatically once d has elapsed.
+func (n *Notifier) Snooze(d time.Duration) error {
+	if n == nil {
+		return nil
+	}
+	n.callMu.Lock()
+	defer n.callMu.Unlock()
+
+	n.snoozedUntil = time.Now().Add(d)
+	note := n.message(Payload{
+		Summary: "Notifications muted",
+		Body:    "Resuming at " + n.snoozedUntil.Format("15:04"),
+	})
+	note.transient = true // Outdated once notifications resume
+	return n.send(note, 0)
+}
+
+// Unsnooze resumes track notifications before the snooze period ends
+func (n *Notifier) Unsnooze() {
+	if n == nil {
+		return
+	}
+	n.callMu.Lock()
+	defer n.callMu.Unlock()
+
+	n.snoozedUntil = time.Time{}
+}
+
+// Clear closes the most recent track notification, so a stale "now
+// playing" banner doesn't linger once playback has ended. Messages and
+// errors are left open. Backends that can't close notifications leave it
+// to expire.
+func (n *Notifier) Clear() error {
+	if n == nil {
+		return nil
+	}
+	n.StopLiveUpdates()
+
+	n.mu.Lock()
+	id := n.current
+	n.mu.Unlock()
+	if id == 0 {
+		return nil
+	}
+	n.forget(id)
+
+	dismisser, ok := n.backend.(Dismisser)
+	if !ok || n.closed.Load() {
+		return nil
+	}
+	if err := dismisser.Dismiss(id); err != nil {
+		n.opts().logger().Debug("closing notification failed", "id", id, "err", err)
+		return fmt.Errorf("failed to close notification %d: %w", id, err)
+	}
+	return nil
+}
+
+// stopped clears the track notification when playback stops, and forgets
+// the track so playing it again notifies
+func (n *Notifier) stopped(track *TrackInfo) error {
+	n.transition(StateStopped)
+	if track != nil {
+		scope := ""
+		if n.opts().DedupScope == DedupPerSource {
+			scope = track.Source
+		}
+		delete(n.lastIDs, scope)
+		delete(n.lastStations, scope)
+		n.album = albumSquash{}
+		n.resetBudget()
+		if track.Source != "" {
+			n.sourceStates[track.Source] = StateStopped
+		}
+	}
+	return n.Clear()
+}
+
+// clearsOnStop reports whether Notify clears on StateStopped
+func (n *Notifier) clearsOnStop() bool {
+	return n != nil && n.opts().ClearOnStop
+}
+
+// DismissAll closes every notification this notifier has shown, for
+// backends that can close notifications
+func (n *Notifier) DismissAll() error {
+	if n == nil {
+		return nil
+	}
+	n.StopLiveUpdates()
+
+	n.mu.Lock()
+	ids := make([]uint32, 0, len(n.shown))
+	for id := range n.shown {
+		ids = append(ids, id)
+	}
+	n.shown = make(map[uint32]*sentNotification)
+	n.replaceID = 0
+	n.current = 0
+	n.last = nil
+	n.mu.Unlock()
+
+	dismisser, ok := n.backend.(Dismisser)
+	if !ok || n.closed.Load() {
+		return nil
+	}
+
+	var firstErr error
+	for _, id := range ids {
+		if err := dismisser.Dismiss(id); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to close notification %d: %w", id, err)
+		}
+	}
+
+	return firstErr
+}
+
+// Reset dismisses all notifications and clears deduplication state, so the
+// next Notify behaves as if the notifier was freshly created
+func (n *Notifier) Reset() error {
+	if n == nil {
+		return nil
+	}
+	n.callMu.Lock()
+	defer n.callMu.Unlock()
+
+	err := n.DismissAll()
+	n.lastIDs = make(map[string]string)
+	n.sourceStates = make(map[string]PlaybackState)
+	n.lastStations = make(map[string]string)
+	n.listenedKey = ""
+	n.album = albumSquash{}
+	n.chapterKey = ""
+	n.resetBudget()
+	if saveErr := n.saveState(); err == nil {
+		err = saveErr
+	}
+	return err
+}
+
+// Capabilities returns the features supported by the notification daemon
+func (n *Notifier) Capabilities() Capabilities {
+	if n == nil {
+		return Capabilities{}
+	}
+	return n.backend.Capabilities()
+}
+
+// GetCapabilities returns the capabilities supported by the notification
+// service, for backends that report them
+func (n *Notifier) GetCapabilities() ([]string, error) {
+	if n == nil {
+		return []string{}, nil
+	}
+
+	lister, ok := n.backend.(CapabilityLister)
+	if !ok {
+		return []string{}, nil
+	}
+	if n.closed.Load() {
+		return nil, ErrClosed
+	}
+
+	caps, err := lister.ListCapabilities()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get capabilities: %w", err)
+	}
+	return caps, nil
+}
+
+// ServerInfo identifies the notification service, for backends that can
+func (n *Notifier) ServerInfo() (ServerInfo, error) {
+	if n == nil {
+		return ServerInfo{}, nil
+	}
+
+	provider, ok := n.backend.(ServerInfoProvider)
+	if !ok {
+		return ServerInfo{}, nil
+	}
+	if n.closed.Load() {
+		return ServerInfo{}, ErrClosed
+	}
+
+	info, err := provider.ServerInfo()
+	if err != nil {
+		return ServerInfo{}, fmt.Errorf("failed to get server information: %w", err)
+	}
+	return info, nil
+}
@@ -0,0 +1,376 @@
+package notifications_test
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/go-music-players/notifications"
+	"github.com/go-music-players/notifications/notificationstest"
+)
+
+// play is one Notify call
+type play struct {
+	track notifications.TrackInfo
+	state notifications.PlaybackState
+}
+
+// newNotifier opens a notifier on a new fake
+func newNotifier(t *testing.T, opts ...notifications.Option) (*notifications.Notifier, *notificationstest.Fake) {
+	t.Helper()
+	fake := notificationstest.NewFake()
+	notifier, err := notificationstest.NewNotifier(fake, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { notifier.Close() })
+	return notifier, fake
+}
+
+func TestNotifyDeduplicates(t *testing.T) {
+	song := notifications.TrackInfo{Title: "Song", Artist: "Band", Album: "Album"}
+	other := notifications.TrackInfo{Title: "Other", Artist: "Band", Album: "Album"}
+	fromSource := func(track notifications.TrackInfo, source string) notifications.TrackInfo {
+		track.Source = source
+		return track
+	}
+	onStation := func(station string) notifications.TrackInfo {
+		return notifications.TrackInfo{Title: "News", Station: station}
+	}
+
+	for _, tt := range []struct {
+		name       string
+		opts       []notifications.Option
+		plays      []play
+		wantShown  int
+		wantReason notifications.SuppressionReason // Of the last call ("" if shown)
+	}{
+		{
+			name:       "same track",
+			plays:      []play{{song, notifications.StatePlaying}, {song, notifications.StatePlaying}},
+			wantShown:  1,
+			wantReason: notifications.SuppressSameTrack,
+		},
+		{
+			name:      "new track",
+			plays:     []play{{song, notifications.StatePlaying}, {other, notifications.StatePlaying}},
+			wantShown: 2,
+		},
+		{
+			name: "whitespace only",
+			plays: []play{
+				{song, notifications.StatePlaying},
+				{notifications.TrackInfo{Title: " Song ", Artist: "Band", Album: "Album"}, notifications.StatePlaying},
+			},
+			wantShown:  1,
+			wantReason: notifications.SuppressSameTrack,
+		},
+		{
+			name:       "paused",
+			plays:      []play{{song, notifications.StatePlaying}, {song, notifications.StatePaused}},
+			wantShown:  1,
+			wantReason: notifications.SuppressPaused,
+		},
+		{
+			name:       "no track",
+			plays:      []play{{notifications.TrackInfo{}, notifications.StatePlaying}},
+			wantShown:  0,
+			wantReason: notifications.SuppressNoTrack,
+		},
+		{
+			name:      "station change",
+			plays:     []play{{onStation("One"), notifications.StatePlaying}, {onStation("Two"), notifications.StatePlaying}},
+			wantShown: 2,
+		},
+		{
+			name: "same station",
+			plays: []play{
+				{onStation("One"), notifications.StatePlaying},
+				{onStation("One"), notifications.StatePlaying},
+			},
+			wantShown:  1,
+			wantReason: notifications.SuppressSameTrack,
+		},
+		{
+			name: "global across sources",
+			plays: []play{
+				{fromSource(song, "mpd"), notifications.StatePlaying},
+				{fromSource(song, "spotify"), notifications.StatePlaying},
+			},
+			wantShown:  1,
+			wantReason: notifications.SuppressSameTrack,
+		},
+		{
+			name: "per source",
+			opts: []notifications.Option{notifications.WithOptions(func(o *notifications.Options) {
+				o.DedupScope = notifications.DedupPerSource
+			})},
+			plays: []play{
+				{fromSource(song, "mpd"), notifications.StatePlaying},
+				{fromSource(song, "spotify"), notifications.StatePlaying},
+				{fromSource(song, "mpd"), notifications.StatePlaying},
+			},
+			wantShown:  2,
+			wantReason: notifications.SuppressSameTrack,
+		},
+		{
+			name: "stations per source",
+			opts: []notifications.Option{notifications.WithOptions(func(o *notifications.Options) {
+				o.DedupScope = notifications.DedupPerSource
+			})},
+			plays: []play{
+				{fromSource(onStation("One"), "radio1"), notifications.StatePlaying},
+				{fromSource(onStation("Two"), "radio2"), notifications.StatePlaying},
+				{fromSource(onStation("One"), "radio1"), notifications.StatePlaying},
+				{fromSource(onStation("Two"), "radio2"), notifications.StatePlaying},
+			},
+			wantShown:  2,
+			wantReason: notifications.SuppressSameTrack,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier, fake := newNotifier(t, tt.opts...)
+			for _, p := range tt.plays {
+				track := p.track
+				if err := notifier.Notify(&track, p.state); err != nil {
+					t.Fatal(err)
+				}
+			}
+			fake.AssertCount(t, tt.wantShown)
+			if reason := notifier.LastDelivery().Reason; reason != tt.wantReason {
+				t.Errorf("last suppression reason = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestSourcePriority(t *testing.T) {
+	mpd := &notifications.TrackInfo{Title: "Song", Artist: "Band", Source: "mpd"}
+	spotify := &notifications.TrackInfo{Title: "Other", Artist: "Band", Source: "spotify"}
+	for _, tt := range []struct {
+		name       string
+		mpdState   notifications.PlaybackState // After mpd played
+		wantShown  int
+		wantReason notifications.SuppressionReason
+	}{
+		{"playing outranks", notifications.StatePlaying, 1, notifications.SuppressLowerPriority},
+		{"paused yields", notifications.StatePaused, 2, ""},
+		{"stopped yields", notifications.StateStopped, 2, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier, fake := newNotifier(t, notifications.WithOptions(func(o *notifications.Options) {
+				o.SourcePriority = []string{"mpd", "spotify"}
+			}))
+			for _, p := range []play{{*mpd, notifications.StatePlaying}, {*mpd, tt.mpdState}, {*spotify, notifications.StatePlaying}} {
+				if err := notifier.Notify(&p.track, p.state); err != nil {
+					t.Fatal(err)
+				}
+			}
+			fake.AssertCount(t, tt.wantShown)
+			if reason := notifier.LastDelivery().Reason; reason != tt.wantReason {
+				t.Errorf("last suppression reason = %q, want %q", reason, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestMaxPerTrack(t *testing.T) {
+	track := &notifications.TrackInfo{Title: "Song", Artist: "Band"}
+	for _, tt := range []struct {
+		name  string
+		reset func(n *notifications.Notifier) error
+	}{
+		{"reset", (*notifications.Notifier).Reset},
+		{"stop", func(n *notifications.Notifier) error { return n.Notify(track, notifications.StateStopped) }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier, fake := newNotifier(t, notifications.WithOptions(func(o *notifications.Options) {
+				o.MaxPerTrack = 2
+				o.ClearOnStop = true
+			}))
+			for i := 0; i < 3; i++ {
+				if err := notifier.NotifyNow(track, notifications.StatePlaying); err != nil {
+					t.Fatal(err)
+				}
+			}
+			fake.AssertCount(t, 2)
+			if reason := notifier.LastDelivery().Reason; reason != notifications.SuppressBudget {
+				t.Errorf("suppression reason = %q, want %q", reason, notifications.SuppressBudget)
+			}
+
+			// Playing the track again starts the budget over
+			if err := tt.reset(notifier); err != nil {
+				t.Fatal(err)
+			}
+			fake.Reset()
+			if err := notifier.Notify(track, notifications.StatePlaying); err != nil {
+				t.Fatal(err)
+			}
+			fake.AssertCount(t, 1)
+		})
+	}
+}
+
+func TestRules(t *testing.T) {
+	timeout := int32(0)
+	for _, tt := range []struct {
+		name  string
+		rule  notifications.Rule
+		track notifications.TrackInfo
+		check func(t *testing.T, call notificationstest.Call)
+	}{
+		{
+			name:  "suppress across a slash",
+			rule:  notifications.Rule{When: map[string]string{"artist": "*dc"}, Suppress: true},
+			track: notifications.TrackInfo{Title: "Thunderstruck", Artist: "AC/DC"},
+		},
+		{
+			name: "source from origin",
+			rule: notifications.Rule{When: map[string]string{"source": "mpd*"}, Suppress: true},
+			track: notifications.TrackInfo{
+				Title:  "Song",
+				Origin: notifications.Origin{Player: "mpd", Instance: "kitchen", Host: "server"},
+			},
+		},
+		{
+			name:  "urgency",
+			rule:  notifications.Rule{When: map[string]string{"album": "*live*"}, Urgency: "critical"},
+			track: notifications.TrackInfo{Title: "Song", Album: "Alive in Berlin"},
+			check: func(t *testing.T, call notificationstest.Call) {
+				if urgency := call.Hints["urgency"]; urgency != byte(2) {
+					t.Errorf("urgency hint = %v, want 2", urgency)
+				}
+			},
+		},
+		{
+			name:  "timeout and icon",
+			rule:  notifications.Rule{When: map[string]string{"state": "playing"}, Timeout: &timeout, Icon: "audio-x-generic"},
+			track: notifications.TrackInfo{Title: "Song"},
+			check: func(t *testing.T, call notificationstest.Call) {
+				if call.Timeout != 0 {
+					t.Errorf("timeout = %d, want 0", call.Timeout)
+				}
+				if call.Icon != "audio-x-generic" {
+					t.Errorf("icon = %q, want audio-x-generic", call.Icon)
+				}
+			},
+		},
+		{
+			name:  "no match",
+			rule:  notifications.Rule{When: map[string]string{"artist": "ABBA", "title": "*"}, Suppress: true},
+			track: notifications.TrackInfo{Title: "Song", Artist: "Band"},
+			check: func(t *testing.T, call notificationstest.Call) {
+				if call.Summary != "Song" {
+					t.Errorf("summary = %q, want Song", call.Summary)
+				}
+			},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			notifier, fake := newNotifier(t, notifications.WithOptions(func(o *notifications.Options) {
+				o.Rules = []notifications.Rule{tt.rule}
+			}))
+			if err := notifier.Notify(&tt.track, notifications.StatePlaying); err != nil {
+				t.Fatal(err)
+			}
+			if tt.check == nil {
+				fake.AssertNone(t)
+				if reason := notifier.LastDelivery().Reason; reason != notifications.SuppressRule {
+					t.Errorf("suppression reason = %q, want %q", reason, notifications.SuppressRule)
+				}
+				return
+			}
+			call, ok := fake.Last()
+			if !ok {
+				t.Fatal("no notification shown")
+			}
+			tt.check(t, call)
+		})
+	}
+}
+
+func TestInvalidRules(t *testing.T) {
+	for _, rule := range []notifications.Rule{
+		{When: map[string]string{"genre": "jazz"}},
+		{When: map[string]string{"artist": "[a-"}},
+		{Urgency: "urgent"},
+	} {
+		fake := notificationstest.NewFake()
+		notifier, err := notificationstest.NewNotifier(fake, notifications.WithOptions(func(o *notifications.Options) {
+			o.Rules = []notifications.Rule{rule}
+		}))
+		if err == nil {
+			notifier.Close()
+			t.Errorf("rule %+v was accepted", rule)
+		}
+	}
+}
+
+func TestCapabilitiesDegrade(t *testing.T) {
+	cover, err := filepath.Abs("sample_art.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	track := &notifications.TrackInfo{Title: "I Will Wait", Artist: "Mumford & Sons", Album: "Babel", ImageURL: cover}
+	next := notifications.Action{ID: "next", Label: "Next", Handler: func(*notifications.TrackInfo) {}}
+
+	for _, tt := range []struct {
+		name        string
+		caps        notifications.Capabilities
+		wantBody    string
+		wantActions []string
+		wantImage   bool
+	}{
+		{
+			name:        "everything",
+			caps:        notifications.Capabilities{Images: true, Actions: true, Markup: true},
+			wantBody:    "<b>Mumford &amp; Sons</b>\n<i>Babel</i>",
+			wantActions: []string{"next"},
+			wantImage:   true,
+		},
+		{
+			name:        "plain text",
+			caps:        notifications.Capabilities{Images: true, Actions: true},
+			wantBody:    "Mumford & Sons\nBabel",
+			wantActions: []string{"next"},
+			wantImage:   true,
+		},
+		{
+			name:     "nothing",
+			wantBody: "Mumford & Sons\nBabel",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := notificationstest.NewFake()
+			fake.SetCapabilities(tt.caps)
+			notifier, err := notificationstest.NewNotifier(fake, notifications.WithActions(next))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer notifier.Close()
+
+			if err := notifier.Notify(track, notifications.StatePlaying); err != nil {
+				t.Fatal(err)
+			}
+			fake.AssertLast(t, "I Will Wait", tt.wantBody)
+			call, _ := fake.Last()
+			if !slices.Equal(call.Actions, tt.wantActions) {
+				t.Errorf("actions = %q, want %q", call.Actions, tt.wantActions)
+			}
+			if _, ok := call.Hints["image-path"]; ok != tt.wantImage {
+				t.Errorf("image-path hint = %v, want one: %v", call.Hints["image-path"], tt.wantImage)
+			}
+		})
+	}
+}
+
+func TestFeaturesRemote(t *testing.T) {
+	local, _ := newNotifier(t)
+	if local.Features().Remote {
+		t.Error("Fake reported as remote")
+	}
+	remote, _ := openRemote(t, "push.example.com")
+	if !remote.Features().Remote {
+		t.Error("RemoteBackend not reported as remote")
+	}
+}
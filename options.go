@@ -0,0 +1,232 @@
+package notifications
+
+import (
+	"context"
+	"time"
+)
+
+// Option configures a Notifier. An Options struct is itself an Option that
+// replaces every setting, so NewNotifier(opts) keeps working; the With
+// functions change one setting each, on top of DefaultOptions or an
+// Options passed before them.
+type Option interface {
+	apply(options *Options)
+}
+
+// apply implements Option by replacing every setting
+func (o Options) apply(options *Options) {
+	*options = o
+}
+
+// opts returns the settings in effect. Callers must not modify them.
+func (n *Notifier) opts() *Options {
+	return n.options.Load()
+}
+
+// configure puts options into effect, compiling their templates
+func (n *Notifier) configure(options Options) error {
+	if err := validateRules(options.Rules); err != nil {
+		return err
+	}
+	if _, err := parseTemplate("copy", options.CopyTemplate); err != nil {
+		return err
+	}
+	effective := options
+	if summary, body := options.templates(); options.Renderer == nil && (summary != "" || body != "") {
+		renderer, err := NewTemplateRenderer(summary, body)
+		if err != nil {
+			return err
+		}
+		effective.Renderer = renderer
+	}
+	if set := options.ChapterTemplate; options.ChapterRenderer == nil && (set.Summary != "" || set.Body != "") {
+		renderer, err := NewTemplateRenderer(set.Summary, set.Body)
+		if err != nil {
+			return err
+		}
+		renderer.(*templateRenderer).base = chapterRenderer{Capabilities: Capabilities{Images: true}}
+		effective.ChapterRenderer = renderer
+	}
+	n.base = options
+	n.options.Store(&effective)
+	return nil
+}
+
+// baseOptions returns the settings Reconfigure applies options on top of
+func (n *Notifier) baseOptions() Options {
+	if n == nil {
+		return Options{}
+	}
+	n.configMu.Lock()
+	defer n.configMu.Unlock()
+	return n.base
+}
+
+// Reconfigure changes a running notifier's settings, applying options on
+// top of the current ones; pass an Options struct to replace them all.
+// Settings read when the notifier was opened keep their values: Backend,
+// FallbackBackends, Store, ArtTimeout, ArtFetcher, Coordinate and those the
+// backend reads from BackendConfig (ImageMode, MaxImageBytes, Logger,
+// LogFile, PushEndpoint, Credentials, Retry). Invalid templates leave the
+// settings unchanged.
+func (n *Notifier) Reconfigure(opts ...Option) error {
+	if n == nil {
+		return nil
+	}
+	n.configMu.Lock()
+	defer n.configMu.Unlock()
+
+	options := n.base
+	for _, option := range opts {
+		if option != nil {
+			option.apply(&options)
+		}
+	}
+	options.Backend = n.base.Backend
+	options.FallbackBackends = n.base.FallbackBackends
+	options.Store = n.base.Store
+	options.ArtTimeout = n.base.ArtTimeout
+	options.ArtFetcher = n.base.ArtFetcher
+	options.ImageMode = n.base.ImageMode
+	options.MaxImageBytes = n.base.MaxImageBytes
+	options.LogFile = n.base.LogFile
+	options.PushEndpoint = n.base.PushEndpoint
+	options.Credentials = n.base.Credentials
+	options.Retry = n.base.Retry
+	options.Coordinate = n.base.Coordinate
+	options.Logger = n.base.Logger
+	return n.configure(options)
+}
+
+// optionFunc adapts a function to Option
+type optionFunc func(options *Options)
+
+// apply implements Option
+func (f optionFunc) apply(options *Options) {
+	f(options)
+}
+
+// newOptions applies options in order, starting from DefaultOptions
+func newOptions(options []Option) Options {
+	o := DefaultOptions("")
+	for _, option := range options {
+		if option != nil {
+			option.apply(&o)
+		}
+	}
+	return o
+}
+
+// WithAppName sets the application name shown in notifications
+func WithAppName(name string) Option {
+	return optionFunc(func(o *Options) { o.AppName = name })
+}
+
+// WithIcon sets the icon name or path
+func WithIcon(icon string) Option {
+	return optionFunc(func(o *Options) { o.Icon = icon })
+}
+
+// WithTimeout sets how long notifications stay up, rounded down to the
+// millisecond. Zero keeps them until dismissed.
+func WithTimeout(timeout time.Duration) Option {
+	return optionFunc(func(o *Options) { o.Timeout = int32(timeout / time.Millisecond) })
+}
+
+// WithNotifyOnPause sets whether pausing shows a notification
+func WithNotifyOnPause(notify bool) Option {
+	return optionFunc(func(o *Options) { o.NotifyOnPause = notify })
+}
+
+// WithReplaceExisting sets whether notifications replace the previous one
+// instead of stacking
+func WithReplaceExisting(replace bool) Option {
+	return optionFunc(func(o *Options) { o.ReplaceExisting = replace })
+}
+
+// WithUrgency sets the urgency of track notifications
+func WithUrgency(urgency Urgency) Option {
+	return optionFunc(func(o *Options) { o.Urgency = urgency })
+}
+
+// WithDesktopEntry sets the player's .desktop file name
+func WithDesktopEntry(entry string) Option {
+	return optionFunc(func(o *Options) { o.DesktopEntry = entry })
+}
+
+// WithRenderer sets the renderer
+func WithRenderer(renderer Renderer) Option {
+	return optionFunc(func(o *Options) { o.Renderer = renderer })
+}
+
+// WithActions adds actions to every track notification
+func WithActions(actions ...Action) Option {
+	return optionFunc(func(o *Options) { o.Actions = append(o.Actions, actions...) })
+}
+
+// WithStore sets where state is persisted between runs
+func WithStore(store Store) Option {
+	return optionFunc(func(o *Options) { o.Store = store })
+}
+
+// WithBackend selects a registered backend and the fallbacks to try in
+// order if it can't be opened
+func WithBackend(name string, fallbacks ...string) Option {
+	return optionFunc(func(o *Options) {
+		o.Backend = name
+		o.FallbackBackends = fallbacks
+	})
+}
+
+// WithOptions changes any settings through a function, for those without
+// a With function of their own
+func WithOptions(change func(options *Options)) Option {
+	return optionFunc(change)
+}
+
+// NotifyOption overrides a setting for one notification, with
+// NotifyWithOptions
+type NotifyOption func(call *callOptions)
+
+// callOptions are the settings overridden for one notification
+type callOptions struct {
+	timeout *int32
+	urgency *Urgency
+	icon    string
+	replace *bool
+	ctx     context.Context
+
+	// sample is SendTest's notification, which leaves the per-track
+	// budget, live updates and the notification to replace alone
+	sample bool
+}
+
+// NotifyTimeout sets how long this notification stays up. Zero keeps it
+// until dismissed.
+func NotifyTimeout(timeout time.Duration) NotifyOption {
+	ms := int32(timeout / time.Millisecond)
+	return func(call *callOptions) { call.timeout = &ms }
+}
+
+// NotifyUrgency sets this notification's urgency, over the renderer's
+func NotifyUrgency(urgency Urgency) NotifyOption {
+	return func(call *callOptions) { call.urgency = &urgency }
+}
+
+// NotifyIcon sets this notification's icon name or path
+func NotifyIcon(icon string) NotifyOption {
+	return func(call *callOptions) { call.icon = icon }
+}
+
+// NotifyContext sets the context this notification's spans are started
+// in, with Options.Tracer
+func NotifyContext(ctx context.Context) NotifyOption {
+	return func(call *callOptions) { call.ctx = ctx }
+}
+
+// NotifyReplace sets whether this notification replaces the current track
+// notification. When false, it pops up separately and the next track
+// replaces the previous notification instead of it.
+func NotifyReplace(replace bool) NotifyOption {
+	return func(call *callOptions) { call.replace = &replace }
+}
@@ -0,0 +1,132 @@
+package notifications_test
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-music-players/notifications"
+	"github.com/go-music-players/notifications/notificationstest"
+)
+
+// performanceBudget is the most allocations each step of the notify path
+// may make, as documented under Performance in the README. Raise a budget
+// only with a reason in the commit message.
+var performanceBudget = []struct {
+	name   string
+	allocs float64
+	run    func(tb testing.TB) func()
+}{
+	{"render", 6, func(tb testing.TB) func() {
+		renderer := notifications.DefaultRenderer{Capabilities: notifications.Capabilities{Images: true, Markup: true}}
+		track := &notifications.TrackInfo{Title: "Song", Artist: "Simon & Garfunkel", Album: "Bookends"}
+		return func() {
+			if _, err := renderer.Render(track, notifications.StatePlaying); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	}},
+	{"repeated notification", 25, func(tb testing.TB) func() {
+		notifier := openNotifier(tb)
+		track := &notifications.TrackInfo{Title: "Song", Artist: "Band", Album: "Album", ImageURL: writeCover(tb, 1000, 800)}
+		return func() {
+			if err := notifier.NotifyNow(track, notifications.StatePlaying); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	}},
+	{"deduplicated notification", 8, func(tb testing.TB) func() {
+		notifier := openNotifier(tb)
+		track := &notifications.TrackInfo{Title: "Song", Artist: "Band", Album: "Album"}
+		return func() {
+			if err := notifier.Notify(track, notifications.StatePlaying); err != nil {
+				tb.Fatal(err)
+			}
+		}
+	}},
+}
+
+func TestPerformanceBudget(t *testing.T) {
+	for _, step := range performanceBudget {
+		t.Run(step.name, func(t *testing.T) {
+			run := step.run(t)
+			run() // Warm up caches
+			if allocs := testing.AllocsPerRun(100, run); allocs > step.allocs {
+				t.Errorf("%v allocations per call, over the budget of %v", allocs, step.allocs)
+			}
+		})
+	}
+}
+
+// writeCover writes a width x height PNG cover into a temporary directory
+func writeCover(tb testing.TB, width, height int) string {
+	tb.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: uint8(x ^ y), A: 0xff})
+		}
+	}
+	path := filepath.Join(tb.TempDir(), "cover.png")
+	f, err := os.Create(path)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+// openNotifier opens a notifier on a fake that shows art
+func openNotifier(tb testing.TB) *notifications.Notifier {
+	tb.Helper()
+	notifier, err := notificationstest.NewNotifier(notificationstest.NewFake())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { notifier.Close() })
+	return notifier
+}
+
+// BenchmarkRepeatedNotification re-sends one track with a 1000x800 cover,
+// as progress and live updates do, so the decoded art is reused
+func BenchmarkRepeatedNotification(b *testing.B) {
+	track := &notifications.TrackInfo{Title: "Song", Artist: "Band", Album: "Album", ImageURL: writeCover(b, 1000, 800)}
+	notifier := openNotifier(b)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := notifier.NotifyNow(track, notifications.StatePlaying); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkChangedCover re-sends one track whose cover file is rewritten
+// before every send, so the art is decoded each time
+func BenchmarkChangedCover(b *testing.B) {
+	track := &notifications.TrackInfo{Title: "Song", Artist: "Band", Album: "Album", ImageURL: writeCover(b, 1000, 800)}
+	notifier := openNotifier(b)
+	modified := time.Now()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		modified = modified.Add(time.Second)
+		if err := os.Chtimes(track.ImageURL, modified, modified); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+		if err := notifier.NotifyNow(track, notifications.StatePlaying); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+//go:build linux
+
+package notifications
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	portalService   = "org.freedesktop.portal.Desktop"
+	portalPath      = "/org/freedesktop/portal/desktop"
+	portalInterface = "org.freedesktop.portal.Notification"
+)
+
+func init() {
+	Register("portal", newPortalBackend)
+}
+
+// portalBackend delivers notifications through the XDG Desktop Portal,
+// which Flatpak and Snap apps can use without extra permissions
+type portalBackend struct {
+	conn    *dbus.Conn
+	config  BackendConfig
+	version uint32 // Notification portal version
+
+	mu     sync.Mutex
+	nextID uint32
+}
+
+// gIcon is a serialized GIcon, as the portal expects icons
+type gIcon struct {
+	Type string
+	Data dbus.Variant
+}
+
+// portalSound is a sound file for version 2 of the portal, which takes it
+// as an open file descriptor
+type portalSound struct {
+	Type string // "file"
+	FD   dbus.UnixFD
+}
+
+// portalMarkupVersion is the first portal version taking markup-body,
+// sounds and display-hint
+const portalMarkupVersion = 2
+
+// newPortalBackend connects to the session bus and checks that the portal
+// provides notifications
+func newPortalBackend(config BackendConfig) (Backend, error) {
+	conn, err := dbus.ConnectSessionBus()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to session bus: %w", diagnoseConnect(err))
+	}
+
+	obj := conn.Object(portalService, portalPath)
+	version, err := obj.GetProperty(portalInterface + ".version")
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("notification portal not available: %w", diagnoseCall(err))
+	}
+
+	b := &portalBackend{conn: conn, config: config, nextID: firstID()}
+	b.version, _ = version.Value().(uint32)
+	if err := b.listen(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to subscribe to portal signals: %w", err)
+	}
+	return b, nil
+}
+
+// Send calls AddNotification. Adding a notification with an existing ID
+// replaces it. Version 1 portals get a plain body and the desktop's sound.
+func (b *portalBackend) Send(note *Notification) (uint32, error) {
+	id := note.ReplacesID
+	if id == 0 {
+		b.mu.Lock()
+		b.nextID++
+		id = b.nextID
+		b.mu.Unlock()
+	}
+
+	notification := map[string]dbus.Variant{
+		"title":    dbus.MakeVariant(note.Summary),
+		"body":     dbus.MakeVariant(note.Body),
+		"priority": dbus.MakeVariant(portalPriority(note.Urgency)),
+	}
+
+	if note.Icon != "" {
+		notification["icon"] = dbus.MakeVariant(gIcon{"themed", dbus.MakeVariant([]string{note.Icon})})
+	}
+
+	// Album art replaces the icon, as the portal has no separate image
+	if note.ImagePath != "" {
+		if data, err := os.ReadFile(note.ImagePath); err == nil {
+			notification["icon"] = dbus.MakeVariant(gIcon{"bytes", dbus.MakeVariant(data)})
+		}
+	}
+
+	var buttons []map[string]dbus.Variant
+	for _, action := range note.Actions {
+		if action.ID == "default" {
+			notification["default-action"] = dbus.MakeVariant(action.ID)
+			continue
+		}
+		buttons = append(buttons, map[string]dbus.Variant{
+			"label":  dbus.MakeVariant(action.Label),
+			"action": dbus.MakeVariant(action.ID),
+		})
+	}
+	if len(buttons) > 0 {
+		notification["buttons"] = dbus.MakeVariant(buttons)
+	}
+
+	if b.version >= portalMarkupVersion {
+		notification["body"] = dbus.MakeVariant(stripMarkup(note.Body))
+		notification["markup-body"] = dbus.MakeVariant(note.Body)
+		if hints := portalDisplayHints(note); len(hints) > 0 {
+			notification["display-hint"] = dbus.MakeVariant(hints)
+		}
+		sound, err := portalSoundFor(note)
+		if err == nil && sound != nil {
+			defer sound.Close()
+			notification["sound"] = dbus.MakeVariant(portalSound{"file", dbus.UnixFD(sound.Fd())})
+		}
+		if note.SuppressSound {
+			notification["sound"] = dbus.MakeVariant("silent")
+		}
+	}
+
+	obj := b.conn.Object(portalService, portalPath)
+	call := obj.Call(portalInterface+".AddNotification", 0, portalID(id), notification)
+	if call.Err != nil {
+		return 0, diagnoseCall(call.Err)
+	}
+	return id, nil
+}
+
+// Dismiss calls RemoveNotification
+func (b *portalBackend) Dismiss(id uint32) error {
+	obj := b.conn.Object(portalService, portalPath)
+	return obj.Call(portalInterface+".RemoveNotification", 0, portalID(id)).Err
+}
+
+// Capabilities returns what the portal supports. It has no timeouts and
+// doesn't report closed notifications; version 2 adds markup.
+func (b *portalBackend) Capabilities() Capabilities {
+	return Capabilities{
+		Images:      true,
+		Actions:     true,
+		Replacement: true,
+		Markup:      b.version >= portalMarkupVersion,
+	}
+}
+
+// Close closes the D-Bus connection
+func (b *portalBackend) Close() error {
+	return b.conn.Close()
+}
+
+// listen routes ActionInvoked signals until the connection is closed
+func (b *portalBackend) listen() error {
+	err := b.conn.AddMatchSignal(
+		dbus.WithMatchObjectPath(portalPath),
+		dbus.WithMatchInterface(portalInterface),
+		dbus.WithMatchMember("ActionInvoked"),
+	)
+	if err != nil {
+		return err
+	}
+
+	signals := make(chan *dbus.Signal, 16)
+	b.conn.Signal(signals)
+
+	go func() {
+		// The channel is closed when the connection is closed
+		for signal := range signals {
+			if signal.Name != portalInterface+".ActionInvoked" || len(signal.Body) < 2 {
+				continue
+			}
+			idText, _ := signal.Body[0].(string)
+			action, _ := signal.Body[1].(string)
+			id, err := strconv.ParseUint(idText, 10, 32)
+			if err != nil || b.config.OnAction == nil {
+				continue
+			}
+			b.config.OnAction(uint32(id), action)
+		}
+	}()
+
+	return nil
+}
+
+// portalID formats a notification ID for the portal, which uses strings
+func portalID(id uint32) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// portalDisplayHints maps a notification's hints to version 2 display
+// hints. A replacement for a new track pops up again, as the D-Bus daemons
+// do, while live updates change the notification quietly.
+func portalDisplayHints(note *Notification) []string {
+	var hints []string
+	if note.Transient {
+		hints = append(hints, "transient")
+	}
+	if note.Timeout == 0 || note.Resident {
+		hints = append(hints, "persistent")
+	}
+	if note.ReplacesID != 0 && !note.Resident {
+		hints = append(hints, "show-as-new")
+	}
+	return hints
+}
+
+// portalSoundFor opens the sound file to play, if any. Sound theme names
+// can't be passed to the portal; they play its default sound.
+func portalSoundFor(note *Notification) (*os.File, error) {
+	if note.SoundFile == "" {
+		return nil, nil
+	}
+	return os.Open(note.SoundFile)
+}
+
+// portalPriority maps an Urgency to the portal's priority values
+func portalPriority(u Urgency) string {
+	switch u {
+	case UrgencyLow:
+		return "low"
+	case UrgencyCritical:
+		return "urgent"
+	default:
+		return "normal"
+	}
+}
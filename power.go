@@ -0,0 +1,41 @@
+package notifications
+
+// Power profiles as power-profiles-daemon names them
+const (
+	ProfilePowerSaver  = "power-saver"
+	ProfileBalanced    = "balanced"
+	ProfilePerformance = "performance"
+)
+
+// PowerSaving is the work a notifier sheds while the system is in a power
+// profile, e.g. on battery, see Options.PowerSaving
+type PowerSaving struct {
+	// SkipArtDownloads shows only local and already cached album art,
+	// rather than waking the network to download or fetch it
+	SkipArtDownloads bool
+
+	// NoLiveUpdates shows live updates, countdowns and lyrics once
+	// without refreshing them, and stops those already running, so their
+	// ticker doesn't keep waking the CPU
+	NoLiveUpdates bool
+}
+
+// activeProfile returns the active power profile ("" if unknown),
+// watching it from the first call on. With no Options.PowerSaving
+// configured, it isn't watched at all.
+func (n *Notifier) activeProfile() string {
+	if len(n.opts().PowerSaving) == 0 {
+		return ""
+	}
+	n.powerOnce.Do(func() { n.power = watchPowerProfile() })
+	return n.power.active()
+}
+
+// powerSaving returns the work to shed in the active power profile
+func (n *Notifier) powerSaving() PowerSaving {
+	profile := n.activeProfile()
+	if profile == "" {
+		return PowerSaving{}
+	}
+	return n.opts().PowerSaving[profile]
+}
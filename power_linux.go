@@ -0,0 +1,100 @@
+//go:build linux
+
+package notifications
+
+import (
+	"sync"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// powerProfilesServices are where power-profiles-daemon publishes the
+// active profile: under UPower's name since version 0.20, and its own
+// before that
+var powerProfilesServices = []struct {
+	name string
+	path dbus.ObjectPath
+}{
+	{"org.freedesktop.UPower.PowerProfiles", "/org/freedesktop/UPower/PowerProfiles"},
+	{"net.hadess.PowerProfiles", "/net/hadess/PowerProfiles"},
+}
+
+// powerProfile follows power-profiles-daemon's active profile, so work
+// can be shed while saving power
+type powerProfile struct {
+	conn *dbus.Conn
+
+	mu      sync.Mutex
+	profile string
+}
+
+// watchPowerProfile subscribes to power-profiles-daemon on the system bus.
+// It returns nil when power-profiles-daemon isn't running, and a nil
+// powerProfile reports no profile.
+func watchPowerProfile() *powerProfile {
+	conn, err := dbus.ConnectSystemBus()
+	if err != nil {
+		return nil
+	}
+
+	for _, service := range powerProfilesServices {
+		match := []dbus.MatchOption{
+			dbus.WithMatchObjectPath(service.path),
+			dbus.WithMatchInterface("org.freedesktop.DBus.Properties"),
+			dbus.WithMatchMember("PropertiesChanged"),
+			dbus.WithMatchArg(0, service.name),
+		}
+		if err := conn.AddMatchSignal(match...); err != nil {
+			break
+		}
+
+		// Read the profile after subscribing so no change is missed in between
+		v, err := conn.Object(service.name, service.path).GetProperty(service.name + ".ActiveProfile")
+		if err != nil {
+			conn.RemoveMatchSignal(match...)
+			continue
+		}
+
+		p := &powerProfile{conn: conn}
+		p.profile, _ = v.Value().(string)
+
+		signals := make(chan *dbus.Signal, 16)
+		conn.Signal(signals)
+		go func() {
+			// The channel is closed when the connection is closed
+			for signal := range signals {
+				if len(signal.Body) < 2 {
+					continue
+				}
+				changed, _ := signal.Body[1].(map[string]dbus.Variant)
+				if profile, ok := changed["ActiveProfile"].Value().(string); ok {
+					p.mu.Lock()
+					p.profile = profile
+					p.mu.Unlock()
+				}
+			}
+		}()
+		return p
+	}
+
+	conn.Close()
+	return nil
+}
+
+// active returns the active profile, e.g. "power-saver" ("" if unknown)
+func (p *powerProfile) active() string {
+	if p == nil {
+		return ""
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.profile
+}
+
+// close stops watching
+func (p *powerProfile) close() error {
+	if p == nil {
+		return nil
+	}
+	return p.conn.Close()
+}
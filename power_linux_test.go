@@ -0,0 +1,152 @@
+package notifications_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-music-players/notifications"
+	"github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/prop"
+)
+
+// powerProfiles is a fake power-profiles-daemon on a private system bus
+type powerProfiles struct {
+	props *prop.Properties
+	name  string
+}
+
+// startPowerProfiles runs a fake power-profiles-daemon under name, at
+// path, with profile active
+func startPowerProfiles(t *testing.T, name string, path dbus.ObjectPath, profile string) *powerProfiles {
+	t.Helper()
+	conn, err := dbus.Connect(startPrivateBus(t, "DBUS_SYSTEM_BUS_ADDRESS"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	props, err := prop.Export(conn, path, prop.Map{
+		name: {"ActiveProfile": {Value: profile, Writable: true, Emit: prop.EmitTrue}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := conn.RequestName(name, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("failed to own %s: %v", name, err)
+	}
+	return &powerProfiles{props: props, name: name}
+}
+
+// switchTo changes the active profile, waiting for notifier to see it
+func (p *powerProfiles) switchTo(t *testing.T, notifier *notifications.Notifier, profile string) {
+	t.Helper()
+	p.props.SetMust(p.name, "ActiveProfile", profile)
+	for deadline := time.Now().Add(5 * time.Second); notifier.Features().PowerProfile != profile; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("power profile = %q, want %q", notifier.Features().PowerProfile, profile)
+		}
+	}
+}
+
+func TestPowerSaving(t *testing.T) {
+	for _, service := range []struct {
+		name string
+		path dbus.ObjectPath
+	}{
+		{"org.freedesktop.UPower.PowerProfiles", "/org/freedesktop/UPower/PowerProfiles"},
+		{"net.hadess.PowerProfiles", "/net/hadess/PowerProfiles"},
+	} {
+		t.Run(service.name, func(t *testing.T) {
+			t.Setenv("XDG_CACHE_HOME", t.TempDir())
+			cover := writeCover(t, 10, 10)
+			var downloads atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				downloads.Add(1)
+				http.ServeFile(w, r, cover)
+			}))
+			defer server.Close()
+
+			profiles := startPowerProfiles(t, service.name, service.path, notifications.ProfileBalanced)
+			notifier, fake := newNotifier(t)
+			if features := notifier.Features(); features.PowerProfile != notifications.ProfileBalanced || !features.RemoteArt || !features.LiveUpdates {
+				t.Errorf("balanced: profile, remote art, live updates = %q, %v, %v", features.PowerProfile, features.RemoteArt, features.LiveUpdates)
+			}
+			notify := func(title, imageURL string) (image string) {
+				t.Helper()
+				track := &notifications.TrackInfo{Title: title, Artist: "Band", ImageURL: imageURL}
+				if err := notifier.Notify(track, notifications.StatePlaying); err != nil {
+					t.Fatal(err)
+				}
+				call, _ := fake.Last()
+				return call.Notification.ImagePath
+			}
+			if notify("One", server.URL+"/one.png") == "" {
+				t.Error("balanced: art wasn't downloaded")
+			}
+
+			profiles.switchTo(t, notifier, notifications.ProfilePowerSaver)
+			if features := notifier.Features(); features.RemoteArt || features.LiveUpdates {
+				t.Errorf("power saver: remote art, live updates = %v, %v, want neither", features.RemoteArt, features.LiveUpdates)
+			}
+			if image := notify("Two", server.URL+"/two.png"); image != "" {
+				t.Errorf("power saver: art was downloaded to %s", image)
+			}
+			if notify("Three", server.URL+"/one.png") == "" {
+				t.Error("power saver: cached art wasn't shown")
+			}
+			if notify("Four", cover) == "" {
+				t.Error("power saver: local art wasn't shown")
+			}
+			if n := downloads.Load(); n != 1 {
+				t.Errorf("downloaded art %d times, want once", n)
+			}
+		})
+	}
+}
+
+func TestPowerSavingLiveUpdates(t *testing.T) {
+	const interval = 2 * time.Millisecond
+	song := &notifications.TrackInfo{Title: "Song", Artist: "Band", Duration: time.Minute}
+	for i := 0; i < 1000; i++ {
+		song.Lyrics = append(song.Lyrics, notifications.LyricLine{At: time.Duration(i) * 5 * interval, Text: fmt.Sprint("Line ", i)})
+	}
+
+	for _, tt := range []struct {
+		name     string
+		profile  string // When the lyrics start
+		saving   notifications.PowerSaving
+		wantLive bool
+	}{
+		{"started saving", notifications.ProfilePowerSaver, notifications.PowerSaving{NoLiveUpdates: true}, false},
+		{"switched to saving", notifications.ProfileBalanced, notifications.PowerSaving{NoLiveUpdates: true}, true},
+		{"not configured", notifications.ProfilePowerSaver, notifications.PowerSaving{SkipArtDownloads: true}, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			profiles := startPowerProfiles(t, "net.hadess.PowerProfiles", "/net/hadess/PowerProfiles", tt.profile)
+			notifier, fake := openCounting(t, notifications.WithOptions(func(o *notifications.Options) {
+				o.LiveUpdateInterval = interval
+				o.PowerSaving = map[string]notifications.PowerSaving{notifications.ProfilePowerSaver: tt.saving}
+			}))
+			if err := notifier.StartLyrics(song); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(50 * interval)
+			if live := fake.sends.Load() > 1; live != tt.wantLive {
+				t.Fatalf("sent %d updates, want live updates: %v", fake.sends.Load(), tt.wantLive)
+			}
+
+			// Running updates stop once saving power
+			profiles.switchTo(t, notifier, notifications.ProfilePowerSaver)
+			time.Sleep(10 * interval) // For the next tick to see it
+			before := fake.sends.Load()
+			time.Sleep(50 * interval)
+			if stopped := fake.sends.Load() == before; stopped != tt.saving.NoLiveUpdates {
+				t.Errorf("sent %d updates after saving power, want them stopped: %v", fake.sends.Load()-before, tt.saving.NoLiveUpdates)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package notifications
+
+// powerProfile is only followed through power-profiles-daemon on Linux
+type powerProfile struct{}
+
+// watchPowerProfile returns nil, which reports no profile
+func watchPowerProfile() *powerProfile {
+	return nil
+}
+
+// active returns the active profile
+func (p *powerProfile) active() string {
+	return ""
+}
+
+// close stops watching
+func (p *powerProfile) close() error {
+	return nil
+}
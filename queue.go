@@ -0,0 +1,200 @@
+package notifications
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	// queueKey is the Store key for notifications held while offline
+	queueKey = "offline-queue"
+
+	// maxQueued caps the notifications held while offline, dropping the
+	// oldest, so a week offline doesn't replay a week of tracks
+	maxQueued = 100
+
+	// queueRetryInterval is how often a non-empty queue is replayed while
+	// the notifier is idle
+	queueRetryInterval = 30 * time.Second
+)
+
+// queuedNotification is a notification for a remote backend held while
+// offline, as kept in the Store
+type queuedNotification struct {
+	Time    time.Time     `json:"time"` // When it was first sent
+	AppName string        `json:"app_name"`
+	Icon    string        `json:"icon,omitempty"`
+	Summary string        `json:"summary"`
+	Body    string        `json:"body,omitempty"`
+	Urgency Urgency       `json:"urgency"`
+	Event   Event         `json:"event"`
+	Track   *TrackInfo    `json:"track,omitempty"`
+	State   PlaybackState `json:"state,omitempty"`
+}
+
+// queues reports whether notifications are held while offline: only for
+// remote backends, since desktop notifications don't need the network
+func (n *Notifier) queues() bool {
+	_, ok := n.backend.(RemoteBackend)
+	return ok
+}
+
+// offline reports whether a notification can't be delivered, because the
+// network is down or the backend failed to reach its destination
+func offline(err error) bool {
+	return errors.Is(err, ErrOffline) || errors.Is(err, ErrTimeout)
+}
+
+// enqueue holds a notification until the destination can be reached
+func (n *Notifier) enqueue(notification *Notification) {
+	queued := queuedNotification{
+		Time:    notification.Time,
+		AppName: notification.AppName,
+		Icon:    notification.Icon,
+		Summary: notification.Summary,
+		Body:    notification.Body,
+		Urgency: notification.Urgency,
+		Event:   notification.Event,
+		State:   notification.State,
+	}
+	if notification.Track != nil {
+		track := *notification.Track
+		track.Lyrics, track.Chapters = nil, nil // Not worth keeping
+		queued.Track = &track
+	}
+
+	n.mu.Lock()
+	n.queue = append(n.queue, queued)
+	if len(n.queue) > maxQueued {
+		n.queue = n.queue[len(n.queue)-maxQueued:]
+	}
+	start := !n.replaying
+	n.replaying = true
+	n.mu.Unlock()
+
+	n.opts().logger().Debug("notification queued while offline", "backend", n.backendName, "summary", notification.Summary)
+	n.saveQueue()
+	if start {
+		go n.replayLoop()
+	}
+}
+
+// replayLoop replays the queue every queueRetryInterval until it is
+// empty or the notifier is closed
+func (n *Notifier) replayLoop() {
+	ticker := time.NewTicker(queueRetryInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if n.closed.Load() {
+			return
+		}
+		n.callMu.Lock()
+		n.replay()
+		n.callMu.Unlock()
+
+		n.mu.Lock()
+		done := len(n.queue) == 0
+		if done {
+			n.replaying = false
+		}
+		n.mu.Unlock()
+		if done {
+			return
+		}
+	}
+}
+
+// replay sends the queued notifications in order, with their original
+// times, once the network is back. It stops at the first failure, keeping
+// the rest. Callers must hold callMu.
+func (n *Notifier) replay() {
+	n.mu.Lock()
+	pending := len(n.queue)
+	n.mu.Unlock()
+	if pending == 0 || !n.art.online() {
+		return
+	}
+
+	for {
+		n.mu.Lock()
+		if len(n.queue) == 0 {
+			n.mu.Unlock()
+			break
+		}
+		queued := n.queue[0]
+		n.mu.Unlock()
+
+		err := n.pace(time.Time{})
+		if err == nil {
+			_, err = n.backend.Send(&Notification{
+				AppName:  queued.AppName,
+				Icon:     queued.Icon,
+				Summary:  queued.Summary,
+				Body:     queued.Body,
+				Urgency:  queued.Urgency,
+				Timeout:  n.opts().Timeout,
+				Progress: -1,
+				Event:    queued.Event,
+				Track:    queued.Track,
+				State:    queued.State,
+				Time:     queued.Time,
+			})
+		}
+		var limited *RateLimitError
+		if errors.As(err, &limited) {
+			n.throttled(limited)
+		}
+		if err != nil && (offline(err) || limited != nil || errors.Is(err, ErrClosed)) {
+			n.opts().logger().Debug("replaying queued notifications failed", "backend", n.backendName, "err", err)
+			break
+		}
+		if err != nil {
+			// Rejected for good, e.g. an unregistered endpoint
+			n.opts().logger().Debug("dropped queued notification", "backend", n.backendName, "err", err)
+		}
+
+		n.mu.Lock()
+		n.queue = n.queue[1:]
+		n.mu.Unlock()
+	}
+	n.saveQueue()
+}
+
+// loadQueue restores the notifications queued in earlier runs
+func (n *Notifier) loadQueue() {
+	if n.opts().Store == nil || !n.queues() {
+		return
+	}
+	data, err := n.opts().Store.Get(queueKey)
+	if err != nil || data == nil {
+		return
+	}
+	var queue []queuedNotification
+	if err := json.Unmarshal(data, &queue); err != nil || len(queue) == 0 {
+		return
+	}
+
+	n.mu.Lock()
+	n.queue = queue
+	n.replaying = true
+	n.mu.Unlock()
+	go n.replayLoop()
+}
+
+// saveQueue persists the queue, so a restart doesn't lose it. Failures
+// only lose queued notifications, so they are logged.
+func (n *Notifier) saveQueue() {
+	if n.opts().Store == nil {
+		return
+	}
+	n.mu.Lock()
+	data, err := json.Marshal(n.queue)
+	n.mu.Unlock()
+	if err == nil {
+		err = n.opts().Store.Put(queueKey, data)
+	}
+	if err != nil {
+		n.opts().logger().Debug("failed to save offline queue", "err", err)
+	}
+}
@@ -0,0 +1,75 @@
+package notifications_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/go-music-players/notifications"
+)
+
+func TestOfflineQueue(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		restart bool // Whether a new notifier on the same store replays the queue
+	}{
+		{"replayed", false},
+		{"replayed after restart", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			store := notifications.NewMemoryStore()
+			opts := []notifications.Option{notifications.WithStore(store), withRateLimit(notifications.RateLimit{})}
+			notifier, fake := openRemote(t, "push.example.com", opts...)
+
+			fake.setErr(fmt.Errorf("%w: no route to host", notifications.ErrOffline))
+			var sent []time.Time
+			for _, title := range []string{"One", "Two"} {
+				sent = append(sent, time.Now())
+				if err := notifier.Notify(&notifications.TrackInfo{Title: title, Artist: "Band"}, notifications.StatePlaying); err != nil {
+					t.Fatal(err)
+				}
+				if status := notifier.LastDelivery().Status; status != notifications.DeliveryQueued {
+					t.Errorf("%s: delivery status = %q, want %q", title, status, notifications.DeliveryQueued)
+				}
+			}
+			fake.AssertNone(t)
+
+			if tt.restart {
+				notifier.Close()
+				notifier, fake = openRemote(t, "push.example.com", opts...)
+			}
+			fake.setErr(nil)
+			time.Sleep(10 * time.Millisecond) // So replayed times differ from now
+			online := time.Now()
+			if err := notifier.Notify(&notifications.TrackInfo{Title: "Three", Artist: "Band"}, notifications.StatePlaying); err != nil {
+				t.Fatal(err)
+			}
+
+			// The queue goes out first, in order and with the times it was made
+			calls := fake.Calls()
+			if len(calls) != 3 {
+				t.Fatalf("sent %d notifications, want 3", len(calls))
+			}
+			for i, title := range []string{"One", "Two"} {
+				note := calls[i].Notification
+				if note.Summary != title {
+					t.Errorf("notification %d = %q, want %q", i+1, note.Summary, title)
+				}
+				if note.Time.Before(sent[i]) || !note.Time.Before(online) {
+					t.Errorf("%s: time = %v, want its original time from %v", title, note.Time, sent[i])
+				}
+			}
+			if summary := calls[2].Summary; summary != "Three" {
+				t.Errorf("notification 3 = %q, want %q", summary, "Three")
+			}
+
+			// The replayed queue is gone from the store too
+			notifier.Close()
+			notifier, fake = openRemote(t, "push.example.com", opts...)
+			if err := notifier.Notify(&notifications.TrackInfo{Title: "Four", Artist: "Band"}, notifications.StatePlaying); err != nil {
+				t.Fatal(err)
+			}
+			fake.AssertCount(t, 1)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package notifications
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimit paces notifications to a remote destination, so several
+// notifiers posting to one service don't get the user's account banned: a
+// burst of Burst notifications goes out at once, then one every Interval
+type RateLimit struct {
+	Interval time.Duration // Between notifications once the burst is spent (0 = no limit)
+	Burst    int           // Notifications sent without waiting (0 = 1)
+
+	// MaxWait gives up on a notification that would wait longer than this,
+	// failing with ErrRateLimited (0 = wait while it stays fresh, see
+	// Options.StaleAfter)
+	MaxWait time.Duration
+}
+
+// RateLimitError is returned by remote backends whose destination told
+// them to slow down, e.g. with HTTP 429. The notifier holds back every
+// notification to the destination for After, then retries this one as
+// for ErrTimeout. It matches ErrRateLimited with errors.Is.
+type RateLimitError struct {
+	Destination string
+	After       time.Duration // How long to hold back (0 if the service didn't say)
+}
+
+func (e *RateLimitError) Error() string {
+	if e.After > 0 {
+		return fmt.Sprintf("%s is rate limiting notifications for %v", e.Destination, e.After)
+	}
+	return e.Destination + " is rate limiting notifications"
+}
+
+// Is matches ErrRateLimited
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// limiter schedules notifications to one destination, for every notifier
+// sending there. It spaces them by the rate limit's interval, letting a
+// burst through first.
+type limiter struct {
+	mu  sync.Mutex
+	tat time.Time // When the next notification would be due with no burst
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*limiter) // By destination
+)
+
+// limiterFor returns the limiter shared by the notifiers sending to
+// destination
+func limiterFor(destination string) *limiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+
+	l := limiters[destination]
+	if l == nil {
+		l = &limiter{}
+		limiters[destination] = l
+	}
+	return l
+}
+
+// reserve books a slot for a notification, returning how long to wait for
+// it. A wait longer than maxWait (if positive) books nothing and returns
+// false.
+func (l *limiter) reserve(limit RateLimit, maxWait time.Duration) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	tat := l.tat
+	if tat.Before(now) {
+		tat = now
+	}
+	burst := max(limit.Burst, 1)
+	wait := tat.Add(-time.Duration(burst-1) * limit.Interval).Sub(now)
+	if wait < 0 {
+		wait = 0
+	}
+	if maxWait > 0 && wait > maxWait {
+		return wait, false
+	}
+	l.tat = tat.Add(limit.Interval)
+	return wait, true
+}
+
+// pause holds back every notification for d, pushing the schedule back so
+// the next one is due once d has passed
+func (l *limiter) pause(limit RateLimit, d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	due := time.Now().Add(d + time.Duration(max(limit.Burst, 1)-1)*limit.Interval)
+	if due.After(l.tat) {
+		l.tat = due
+	}
+}
+
+// pace waits for a slot in the remote destination's schedule. It fails
+// with ErrRateLimited when the wait would exceed RateLimit.MaxWait or make
+// the notification stale.
+func (n *Notifier) pace(deadline time.Time) error {
+	remote, ok := n.backend.(RemoteBackend)
+	limit := n.opts().RemoteRateLimit
+	if !ok || limit.Interval <= 0 {
+		return nil
+	}
+
+	maxWait := limit.MaxWait
+	if !deadline.IsZero() {
+		untilStale := time.Until(deadline)
+		if untilStale <= 0 {
+			untilStale = time.Nanosecond
+		}
+		if maxWait <= 0 || untilStale < maxWait {
+			maxWait = untilStale
+		}
+	}
+	destination := remote.Destination()
+	wait, ok := limiterFor(destination).reserve(limit, maxWait)
+	if !ok {
+		return fmt.Errorf("%w: %s would take %v", ErrRateLimited, destination, wait.Round(time.Millisecond))
+	}
+	if wait > 0 {
+		n.opts().logger().Debug("pacing notification", "destination", destination, "wait", wait)
+		time.Sleep(wait)
+		if n.closed.Load() {
+			return ErrClosed
+		}
+	}
+	return nil
+}
+
+// throttled holds back the destination's notifications after it answered
+// with a RateLimitError
+func (n *Notifier) throttled(err *RateLimitError) {
+	limit := n.opts().RemoteRateLimit
+	after := err.After
+	if after <= 0 {
+		after = limit.Interval
+	}
+	limiterFor(err.Destination).pause(limit, after)
+}
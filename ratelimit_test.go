@@ -0,0 +1,151 @@
+package notifications_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-music-players/notifications"
+	"github.com/go-music-players/notifications/notificationstest"
+)
+
+// remoteFake is a Fake delivering to a remote destination, which fails
+// every send with err while it's set
+type remoteFake struct {
+	*notificationstest.Fake
+	destination string
+
+	mu  sync.Mutex
+	err error
+}
+
+func (f *remoteFake) Destination() string { return f.destination }
+
+func (f *remoteFake) Send(note *notifications.Notification) (uint32, error) {
+	f.mu.Lock()
+	err := f.err
+	f.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	return f.Fake.Send(note)
+}
+
+// setErr makes sends fail with err, or succeed again for nil
+func (f *remoteFake) setErr(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// remoteBackends numbers the backends openRemote registers
+var remoteBackends atomic.Int32
+
+// openRemote opens a notifier on a new remote fake sending to destination
+func openRemote(t *testing.T, destination string, opts ...notifications.Option) (*notifications.Notifier, *remoteFake) {
+	t.Helper()
+	fake := &remoteFake{Fake: notificationstest.NewFake(), destination: destination}
+	name := fmt.Sprintf("remote-fake-%d", remoteBackends.Add(1))
+	notifications.Register(name, func(config notifications.BackendConfig) (notifications.Backend, error) {
+		if _, err := fake.Factory(config); err != nil {
+			return nil, err
+		}
+		return fake, nil
+	})
+	notifier, err := notifications.NewNotifier(append(opts, notifications.WithBackend(name))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { notifier.Close() })
+	return notifier, fake
+}
+
+// withRateLimit sets Options.RemoteRateLimit
+func withRateLimit(limit notifications.RateLimit) notifications.Option {
+	return notifications.WithOptions(func(o *notifications.Options) { o.RemoteRateLimit = limit })
+}
+
+func TestRateLimitShared(t *testing.T) {
+	const interval = 100 * time.Millisecond
+	song := &notifications.TrackInfo{Title: "Song", Artist: "Band"}
+	for _, tt := range []struct {
+		name        string
+		destination string // Of the second notifier
+		maxWait     time.Duration
+		wantErr     error
+		wantWait    time.Duration // At least
+	}{
+		{"same destination waits", "push.example.com", 0, nil, interval / 2},
+		{"same destination gives up", "push.example.com", interval / 10, notifications.ErrRateLimited, 0},
+		{"other destination", "other.example.com", interval / 10, nil, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// The limiters outlive the test, so every run gets its own host
+			first, firstFake := openRemote(t, t.Name()+"/push.example.com", withRateLimit(notifications.RateLimit{Interval: interval}))
+			second, secondFake := openRemote(t, t.Name()+"/"+tt.destination,
+				withRateLimit(notifications.RateLimit{Interval: interval, MaxWait: tt.maxWait}))
+
+			if err := first.Notify(song, notifications.StatePlaying); err != nil {
+				t.Fatal(err)
+			}
+			firstFake.AssertCount(t, 1)
+
+			start := time.Now()
+			err := second.Notify(song, notifications.StatePlaying)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("second notifier: err = %v, want %v", err, tt.wantErr)
+			}
+			if waited := time.Since(start); waited < tt.wantWait {
+				t.Errorf("second notifier waited %v, want at least %v", waited, tt.wantWait)
+			}
+			if tt.wantErr != nil {
+				secondFake.AssertNone(t)
+			} else {
+				secondFake.AssertCount(t, 1)
+			}
+		})
+	}
+}
+
+func TestRateLimitBackoff(t *testing.T) {
+	song := &notifications.TrackInfo{Title: "Song", Artist: "Band"}
+	for _, tt := range []struct {
+		name    string
+		limited bool // Whether the destination rate limits the first send
+		wantErr error
+	}{
+		{"rate limited", true, notifications.ErrRateLimited},
+		{"delivered", false, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// The interval alone lets the second notifier through in time
+			destination := t.Name() + "/push.example.com"
+			limit := notifications.RateLimit{Interval: 10 * time.Millisecond, MaxWait: 200 * time.Millisecond}
+			first, firstFake := openRemote(t, destination, withRateLimit(limit), notifications.WithOptions(func(o *notifications.Options) {
+				o.Retry = notifications.RetryPolicy{} // Fail at once
+			}))
+			second, secondFake := openRemote(t, destination, withRateLimit(limit))
+
+			if tt.limited {
+				firstFake.setErr(&notifications.RateLimitError{Destination: destination, After: time.Second})
+			}
+			if err := first.Notify(song, notifications.StatePlaying); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("first notifier: err = %v, want %v", err, tt.wantErr)
+			}
+
+			// The destination's answer holds back every notifier sending there
+			err := second.Notify(song, notifications.StatePlaying)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("second notifier: err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				secondFake.AssertNone(t)
+			} else {
+				secondFake.AssertCount(t, 1)
+			}
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package notifications
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Urgency is the importance of a notification.
+// The zero value is UrgencyNormal.
+type Urgency int
+
+const (
+	UrgencyNormal Urgency = iota
+	UrgencyLow
+	UrgencyCritical
+)
+
+// Payload is a rendered notification, independent of how it is delivered
+type Payload struct {
+	Summary  string   // Notification title
+	Body     string   // Notification text
+	Icon     string   // Icon override (empty uses the app icon)
+	ImageURL string   // Image to attach (empty for none)
+	Urgency  Urgency  // Importance of the notification
+	Actions  []Action // Buttons to show
+	Markup   bool     // Body is markup rather than plain text
+}
+
+// Renderer decides what a notification says for a track and state
+type Renderer interface {
+	Render(track *TrackInfo, state PlaybackState) (Payload, error)
+}
+
+// RendererFunc adapts an ordinary function to the Renderer interface
+type RendererFunc func(track *TrackInfo, state PlaybackState) (Payload, error)
+
+// Render calls f(track, state)
+func (f RendererFunc) Render(track *TrackInfo, state PlaybackState) (Payload, error) {
+	return f(track, state)
+}
+
+// DefaultRenderer renders the title as summary and artist/album (or station)
+// as body, prefixing the body with ⏸ when paused
+type DefaultRenderer struct {
+	ShowLoudness bool         // Append loudness/ReplayGain line
+	Capabilities Capabilities // What the backend can display
+}
+
+// Render implements Renderer
+func (r DefaultRenderer) Render(track *TrackInfo, state PlaybackState) (Payload, error) {
+	// With markup, show the artist in bold and the album in italics;
+	// metadata must then be escaped
+	markup := r.Capabilities.Markup
+	artist, album, station := track.Artist, track.Album, track.Station
+	if markup {
+		artist = "<b>" + EscapeMarkup(artist) + "</b>"
+		album = "<i>" + EscapeMarkup(album) + "</i>"
+		station = EscapeMarkup(station)
+	}
+
+	// Build notification body
+	var body string
+	if track.Artist != "" && track.Album != "" {
+		body = artist + "\n" + album
+	} else if track.Artist != "" {
+		body = artist
+	} else if track.Station != "" {
+		body = station
+	} else {
+		body = "Now Playing"
+	}
+
+	if r.ShowLoudness {
+		if line := loudnessLine(track); line != "" {
+			body += "\n" + line
+		}
+	}
+
+	// Add state indicator if paused
+	if state == StatePaused {
+		body = "⏸ " + body
+	}
+
+	// Notification summary (title)
+	summary := track.Title
+	if summary == "" {
+		summary = "Now Playing"
+	}
+
+	payload := Payload{
+		Summary: summary,
+		Body:    body,
+		Urgency: UrgencyNormal,
+		Markup:  markup,
+	}
+	if r.Capabilities.Images {
+		payload.ImageURL = track.ImageURL
+	}
+
+	return payload, nil
+}
+
+// renderer returns the configured renderer or the default one
+func (o Options) renderer(caps Capabilities) Renderer {
+	if o.Renderer != nil {
+		return o.Renderer
+	}
+	return DefaultRenderer{
+		ShowLoudness: o.ShowLoudness,
+		Capabilities: caps,
+	}
+}
+
+// withBodyLine wraps a renderer to append a line to the body
+func withBodyLine(renderer Renderer, line string) Renderer {
+	return RendererFunc(func(track *TrackInfo, state PlaybackState) (Payload, error) {
+		payload, err := renderer.Render(track, state)
+		if err != nil {
+			return payload, err
+		}
+		text := line
+		if payload.Markup {
+			text = EscapeMarkup(text)
+		}
+		if payload.Body != "" {
+			payload.Body += "\n"
+		}
+		payload.Body += text
+		return payload, nil
+	})
+}
+
+// withSummaryPrefix wraps a renderer to prefix the summary
+func withSummaryPrefix(renderer Renderer, prefix string) Renderer {
+	return RendererFunc(func(track *TrackInfo, state PlaybackState) (Payload, error) {
+		payload, err := renderer.Render(track, state)
+		payload.Summary = prefix + payload.Summary
+		return payload, err
+	})
+}
+
+// formatPause formats a pause length for display, e.g. "12 min" or "1 h 5 min"
+func formatPause(d time.Duration) string {
+	minutes := int(d.Round(time.Minute) / time.Minute)
+	switch {
+	case minutes < 1:
+		return fmt.Sprintf("%d s", int(d.Round(time.Second)/time.Second))
+	case minutes < 60:
+		return fmt.Sprintf("%d min", minutes)
+	case minutes%60 == 0:
+		return fmt.Sprintf("%d h", minutes/60)
+	default:
+		return fmt.Sprintf("%d h %d min", minutes/60, minutes%60)
+	}
+}
+
+// loudnessLine formats loudness information, e.g. "-9.1 LUFS · RG -8.9 dB"
+func loudnessLine(track *TrackInfo) string {
+	var parts []string
+	if track.Loudness != 0 {
+		parts = append(parts, fmt.Sprintf("%.1f LUFS", track.Loudness))
+	}
+	if track.ReplayGain != 0 {
+		parts = append(parts, fmt.Sprintf("RG %+.1f dB", track.ReplayGain))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// stationRenderer returns the renderer used for station changes
+func (o Options) stationRenderer(caps Capabilities) Renderer {
+	if o.StationRenderer != nil {
+		return o.StationRenderer
+	}
+	return o.renderer(caps)
+}
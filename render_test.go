@@ -0,0 +1,60 @@
+package notifications
+
+import "testing"
+
+func TestDefaultRenderer(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		track       TrackInfo
+		state       PlaybackState
+		caps        Capabilities
+		wantSummary string
+		wantBody    string
+	}{
+		{"artist and album", TrackInfo{Title: "Song", Artist: "Band", Album: "Album"}, StatePlaying, Capabilities{}, "Song", "Band\nAlbum"},
+		{"markup", TrackInfo{Title: "Song", Artist: "Simon & Garfunkel", Album: "Bookends"}, StatePlaying, Capabilities{Markup: true}, "Song", "<b>Simon &amp; Garfunkel</b>\n<i>Bookends</i>"},
+		{"artist only", TrackInfo{Title: "Song", Artist: "Band"}, StatePlaying, Capabilities{}, "Song", "Band"},
+		{"station", TrackInfo{Title: "News", Station: "Radio One"}, StatePlaying, Capabilities{}, "News", "Radio One"},
+		{"paused", TrackInfo{Title: "Song", Artist: "Band"}, StatePaused, Capabilities{}, "Song", "⏸ Band"},
+		{"nothing known", TrackInfo{}, StatePlaying, Capabilities{}, "Now Playing", "Now Playing"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := DefaultRenderer{Capabilities: tt.caps}.Render(&tt.track, tt.state)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if payload.Summary != tt.wantSummary || payload.Body != tt.wantBody {
+				t.Errorf("rendered %q / %q, want %q / %q", payload.Summary, payload.Body, tt.wantSummary, tt.wantBody)
+			}
+			if payload.Markup != tt.caps.Markup {
+				t.Errorf("markup = %v, want %v", payload.Markup, tt.caps.Markup)
+			}
+		})
+	}
+}
+
+func TestWithBodyLine(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		caps     Capabilities
+		wantBody string
+	}{
+		{"markup", Capabilities{Markup: true}, "<b>Band</b>\nRock &amp; Roll"},
+		{"plain text", Capabilities{}, "Band\nRock & Roll"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			renderer := withBodyLine(DefaultRenderer{Capabilities: tt.caps}, "Rock & Roll")
+			track := TrackInfo{Title: "Song", Artist: "Band"}
+			// Every render escapes the line afresh, not the last render's result
+			for i := 0; i < 3; i++ {
+				payload, err := renderer.Render(&track, StatePlaying)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if payload.Body != tt.wantBody {
+					t.Fatalf("render %d: body = %q, want %q", i+1, payload.Body, tt.wantBody)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package notifications
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Rule changes how matching track notifications are shown, for routing
+// that would otherwise need a custom renderer. Rules can be kept in a
+// config file:
+//
+//	{"when": {"source": "spotify", "album": "*podcast*"}, "suppress": true}
+type Rule struct {
+	// When maps track fields to patterns, all of which must match. Fields
+	// are source, player, instance, host, title, artist, album, station and
+	// state; patterns are case-insensitive and may use path.Match
+	// wildcards, except that "/" is an ordinary character: "*" matches
+	// "AC/DC" and "mpd*" matches "mpd/kitchen@host". An empty When matches
+	// every track.
+	When map[string]string `json:"when,omitempty"`
+
+	Suppress bool   `json:"suppress,omitempty"`   // Don't show matching tracks
+	Urgency  string `json:"urgency,omitempty"`    // "low", "normal" or "critical"
+	Timeout  *int32 `json:"timeout_ms,omitempty"` // Milliseconds (0 = never expire)
+	Icon     string `json:"icon,omitempty"`       // Icon name or path
+}
+
+// ruleFields extract the fields rules can match on
+var ruleFields = map[string]func(track *TrackInfo, state PlaybackState) string{
+	"source":   func(t *TrackInfo, _ PlaybackState) string { return t.Source },
+	"player":   func(t *TrackInfo, _ PlaybackState) string { return t.Origin.Player },
+	"instance": func(t *TrackInfo, _ PlaybackState) string { return t.Origin.Instance },
+	"host":     func(t *TrackInfo, _ PlaybackState) string { return t.Origin.Host },
+	"title":    func(t *TrackInfo, _ PlaybackState) string { return t.Title },
+	"artist":   func(t *TrackInfo, _ PlaybackState) string { return t.Artist },
+	"album":    func(t *TrackInfo, _ PlaybackState) string { return t.Album },
+	"station":  func(t *TrackInfo, _ PlaybackState) string { return t.Station },
+	"state":    func(_ *TrackInfo, s PlaybackState) string { return string(s) },
+}
+
+// validateRules checks rules for unknown fields, bad patterns and
+// urgencies, so mistakes surface when settings are applied
+func validateRules(rules []Rule) error {
+	for i, rule := range rules {
+		for field, pattern := range rule.When {
+			if _, ok := ruleFields[field]; !ok {
+				return fmt.Errorf("invalid rule %d: unknown field %q", i+1, field)
+			}
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid rule %d: bad pattern %q for %s", i+1, pattern, field)
+			}
+		}
+		if rule.Urgency != "" {
+			if _, ok := parseUrgency(rule.Urgency); !ok {
+				return fmt.Errorf("invalid rule %d: unknown urgency %q", i+1, rule.Urgency)
+			}
+		}
+	}
+	return nil
+}
+
+// matches reports whether the rule applies to a track
+func (r Rule) matches(track *TrackInfo, state PlaybackState) bool {
+	for field, pattern := range r.When {
+		value := ruleFields[field](track, state)
+		if !globMatch(strings.ToLower(pattern), strings.ToLower(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+// globMatch reports whether name matches the path.Match pattern, treating
+// "/" as an ordinary character. Malformed patterns match nothing.
+func globMatch(pattern, name string) bool {
+	px, nx := 0, 0
+	starPx, starNx := -1, 0 // Where to resume after the last "*"
+	for px < len(pattern) || nx < len(name) {
+		if px < len(pattern) {
+			switch c := pattern[px]; c {
+			case '*':
+				starPx, starNx = px, nx
+				px++
+				continue
+			case '?':
+				if nx < len(name) {
+					_, width := utf8.DecodeRuneInString(name[nx:])
+					px++
+					nx += width
+					continue
+				}
+			case '[':
+				if nx < len(name) {
+					r, width := utf8.DecodeRuneInString(name[nx:])
+					matched, end := matchClass(pattern[px:], r)
+					if end < 0 {
+						return false
+					}
+					if matched {
+						px += end
+						nx += width
+						continue
+					}
+				}
+			default:
+				literal := px
+				if c == '\\' {
+					if literal++; literal == len(pattern) {
+						return false
+					}
+				}
+				if nx < len(name) && pattern[literal] == name[nx] {
+					px = literal + 1
+					nx++
+					continue
+				}
+			}
+		}
+		// Mismatch: let the last "*" swallow one more character
+		if starPx < 0 || starNx >= len(name) {
+			return false
+		}
+		_, width := utf8.DecodeRuneInString(name[starNx:])
+		starNx += width
+		px, nx = starPx+1, starNx
+	}
+	return true
+}
+
+// matchClass matches r against the character class at the start of
+// pattern, returning the length of the class (-1 if malformed)
+func matchClass(pattern string, r rune) (matched bool, end int) {
+	i := 1
+	negated := i < len(pattern) && pattern[i] == '^'
+	if negated {
+		i++
+	}
+	for ranges := 0; ; ranges++ {
+		if i >= len(pattern) {
+			return false, -1
+		}
+		if pattern[i] == ']' && ranges > 0 {
+			return matched != negated, i + 1
+		}
+		lo, width := classChar(pattern[i:])
+		if width < 0 {
+			return false, -1
+		}
+		i += width
+		hi := lo
+		if i+1 < len(pattern) && pattern[i] == '-' && pattern[i+1] != ']' {
+			if hi, width = classChar(pattern[i+1:]); width < 0 {
+				return false, -1
+			}
+			i += 1 + width
+		}
+		if lo <= r && r <= hi {
+			matched = true
+		}
+	}
+}
+
+// classChar decodes one, possibly escaped, character of a character class
+func classChar(pattern string) (r rune, width int) {
+	if pattern[0] != '\\' {
+		r, width = utf8.DecodeRuneInString(pattern)
+		return r, width
+	}
+	if len(pattern) == 1 {
+		return 0, -1
+	}
+	r, width = utf8.DecodeRuneInString(pattern[1:])
+	return r, width + 1
+}
+
+// applyRules evaluates the rules for a track in order, adding the matching
+// ones' changes to call; later rules override earlier ones. It reports
+// whether a matching rule suppresses the track.
+func applyRules(rules []Rule, track *TrackInfo, state PlaybackState, call *callOptions) (suppress bool) {
+	for _, rule := range rules {
+		if !rule.matches(track, state) {
+			continue
+		}
+		if rule.Suppress {
+			return true
+		}
+		if urgency, ok := parseUrgency(rule.Urgency); ok {
+			NotifyUrgency(urgency)(call)
+		}
+		if rule.Timeout != nil {
+			NotifyTimeout(time.Duration(*rule.Timeout) * time.Millisecond)(call)
+		}
+		if rule.Icon != "" {
+			NotifyIcon(rule.Icon)(call)
+		}
+	}
+	return false
+}
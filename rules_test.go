@@ -0,0 +1,35 @@
+package notifications
+
+import "testing"
+
+func TestGlobMatch(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"", "", true},
+		{"abba", "abba", true},
+		{"abba", "abb", false},
+		{"*", "", true},
+		{"*", "ac/dc", true},
+		{"*dc", "ac/dc", true},
+		{"ac*", "ac/dc", true},
+		{"mpd*", "mpd/kitchen@host", true},
+		{"*/kitchen@*", "mpd/kitchen@host", true},
+		{"a*b*c", "axxbyyc", true},
+		{"a*b*c", "axxbyy", false},
+		{"?", "é", true},
+		{"??", "é", false},
+		{"[a-c]at", "bat", true},
+		{"[^a-c]at", "bat", false},
+		{"[^a-c]at", "hat", true},
+		{`\*`, "*", true},
+		{`\*`, "x", false},
+		{"[a-", "a", false},
+		{`ab\`, "ab", false},
+	} {
+		if got := globMatch(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("globMatch(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package notifications
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Store persists the notifier's state between runs. Implementations must
+// be safe for concurrent use.
+type Store interface {
+	// Get returns the value stored under key, or nil if there is none
+	Get(key string) ([]byte, error)
+
+	// Put stores value under key, replacing any previous value
+	Put(key string, value []byte) error
+
+	// Delete removes key; deleting a missing key is not an error
+	Delete(key string) error
+}
+
+// FileStore keeps each key in its own file in a directory
+type FileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileStore creates a store in dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create store: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Get reads the file for key
+func (s *FileStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := os.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Put writes the file for key atomically, so a crash never leaves a
+// truncated value
+func (s *FileStore) Put(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(value); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(key))
+}
+
+// Delete removes the file for key
+func (s *FileStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// path maps a key to a file name that is safe whatever the key contains
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, cacheKey(key))
+}
+
+// MemoryStore keeps state in memory, for tests and for embedding the
+// notifier in processes that persist state their own way
+type MemoryStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{values: make(map[string][]byte)}
+}
+
+// Get returns a copy of the value for key
+func (s *MemoryStore) Get(key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.values[key]
+	if !ok {
+		return nil, nil
+	}
+	return append([]byte{}, value...), nil
+}
+
+// Put stores a copy of value under key
+func (s *MemoryStore) Put(key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[key] = append([]byte{}, value...)
+	return nil
+}
+
+// Delete removes key
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, key)
+	return nil
+}
+
+// SQLStore keeps state in a table of a SQLite database, for deployments
+// that already keep their data in one. Open the database with the SQLite
+// driver of your choice (e.g. modernc.org/sqlite or mattn/go-sqlite3), so
+// this package doesn't depend on one.
+type SQLStore struct {
+	db *sql.DB
+}
+
+// NewSQLStore creates a store in db, creating its notifier_state table if
+// needed
+func NewSQLStore(db *sql.DB) (*SQLStore, error) {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS notifier_state (key TEXT PRIMARY KEY, value BLOB NOT NULL)`); err != nil {
+		return nil, fmt.Errorf("failed to create store: %w", err)
+	}
+	return &SQLStore{db: db}, nil
+}
+
+// Get reads the row for key
+func (s *SQLStore) Get(key string) ([]byte, error) {
+	var value []byte
+	err := s.db.QueryRow(`SELECT value FROM notifier_state WHERE key = ?`, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return value, err
+}
+
+// Put inserts or replaces the row for key
+func (s *SQLStore) Put(key string, value []byte) error {
+	if value == nil {
+		value = []byte{} // The column is NOT NULL
+	}
+	_, err := s.db.Exec(`INSERT INTO notifier_state (key, value) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET value = excluded.value`, key, value)
+	return err
+}
+
+// Delete removes the row for key
+func (s *SQLStore) Delete(key string) error {
+	_, err := s.db.Exec(`DELETE FROM notifier_state WHERE key = ?`, key)
+	return err
+}
+
+const (
+	// stateKey is the Store key for the notifier's state
+	stateKey = "notifier-state"
+
+	// stateMaxAge is how long remembered tracks suppress notifications
+	// after a restart; playing the same track the next day should notify
+	stateMaxAge = time.Hour
+)
+
+// persistedState is what the notifier remembers between runs, so
+// restarting the player neither re-announces the current track nor stacks
+// a second popup next to the previous one
+type persistedState struct {
+	Backend   string            `json:"backend"`
+	ReplaceID uint32            `json:"replace_id,omitempty"`
+	LastIDs   map[string]string `json:"last_ids,omitempty"`
+	Saved     time.Time         `json:"saved"`
+}
+
+// loadState restores persisted state. Missing or unreadable state is
+// ignored, as it only avoids a duplicate notification.
+func (n *Notifier) loadState() {
+	if n.opts().Store == nil {
+		return
+	}
+	data, err := n.opts().Store.Get(stateKey)
+	if err != nil || data == nil {
+		return
+	}
+
+	var state persistedState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return
+	}
+
+	if time.Since(state.Saved) < stateMaxAge {
+		for scope, id := range state.LastIDs {
+			n.lastIDs[scope] = id
+		}
+	}
+
+	// IDs are only meaningful to the backend that issued them
+	if state.Backend == n.backendName && n.opts().ReplaceExisting {
+		n.mu.Lock()
+		n.replaceID = state.ReplaceID
+		n.mu.Unlock()
+	}
+}
+
+// saveState persists the current state
+func (n *Notifier) saveState() error {
+	if n.opts().Store == nil {
+		return nil
+	}
+
+	n.mu.Lock()
+	state := persistedState{
+		Backend:   n.backendName,
+		ReplaceID: n.replaceID,
+		LastIDs:   n.lastIDs,
+		Saved:     time.Now(),
+	}
+	data, err := json.Marshal(state)
+	n.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := n.opts().Store.Put(stateKey, data); err != nil {
+		return fmt.Errorf("failed to save state: %w", err)
+	}
+	return n.saveStats()
+}
+
+// statsKey is the Store key for Stats and StatsByDay
+const statsKey = "notifier-stats"
+
+// persistedStats is how Stats and StatsByDay are kept
+type persistedStats struct {
+	Total Stats   `json:"total"`
+	Days  []Stats `json:"days,omitempty"`
+}
+
+// loadStats restores the counts of earlier runs, so they accumulate
+func (n *Notifier) loadStats() {
+	if n.opts().Store == nil {
+		return
+	}
+	data, err := n.opts().Store.Get(statsKey)
+	if err != nil || data == nil {
+		return
+	}
+	var stats persistedStats
+	if err := json.Unmarshal(data, &stats); err != nil || stats.Total.Since.IsZero() {
+		return
+	}
+
+	n.mu.Lock()
+	n.stats = stats.Total
+	n.statsByDay = stats.Days
+	n.mu.Unlock()
+}
+
+// saveStats persists the counts. They are saved along with the state and
+// when the notifier is closed, not after every notification.
+func (n *Notifier) saveStats() error {
+	if n.opts().Store == nil {
+		return nil
+	}
+
+	n.mu.Lock()
+	data, err := json.Marshal(persistedStats{Total: n.stats, Days: n.statsByDay})
+	n.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := n.opts().Store.Put(statsKey, data); err != nil {
+		return fmt.Errorf("failed to save stats: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package notifications_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-music-players/notifications"
+)
+
+func TestStores(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		open func(t *testing.T) notifications.Store
+	}{
+		{"file", func(t *testing.T) notifications.Store {
+			store, err := notifications.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return store
+		}},
+		{"memory", func(*testing.T) notifications.Store { return notifications.NewMemoryStore() }},
+		{"sql", func(t *testing.T) notifications.Store {
+			db, err := sql.Open("fakesql", t.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { db.Close() })
+			store, err := notifications.NewSQLStore(db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return store
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			store := tt.open(t)
+			get := func(key string, want []byte) {
+				t.Helper()
+				value, err := store.Get(key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want == nil && value != nil || string(value) != string(want) {
+					t.Errorf("Get(%q) = %q, want %q", key, value, want)
+				}
+			}
+
+			get("missing", nil)
+			if err := store.Put("state", []byte("one")); err != nil {
+				t.Fatal(err)
+			}
+			get("state", []byte("one"))
+			if err := store.Put("state", []byte("two")); err != nil {
+				t.Fatal(err)
+			}
+			get("state", []byte("two"))
+			if err := store.Put("empty", nil); err != nil {
+				t.Fatal(err)
+			}
+			get("empty", []byte{})
+			if err := store.Delete("state"); err != nil {
+				t.Fatal(err)
+			}
+			get("state", nil)
+			get("empty", []byte{})
+			if err := store.Delete("missing"); err != nil {
+				t.Errorf("deleting a missing key: %v", err)
+			}
+		})
+	}
+}
+
+func init() {
+	sql.Register("fakesql", &fakeSQL{dbs: make(map[string]*fakeDB)})
+}
+
+// fakeSQL is a database/sql driver that understands just the statements
+// SQLStore runs, keyed by their text, so the test fails if they change
+// without being tried against SQLite
+type fakeSQL struct {
+	mu  sync.Mutex
+	dbs map[string]*fakeDB
+}
+
+// fakeDB is the notifier_state table, nil until it's created
+type fakeDB struct {
+	mu   sync.Mutex
+	rows map[string][]byte
+}
+
+func (d *fakeSQL) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	db, ok := d.dbs[name]
+	if !ok {
+		db = &fakeDB{}
+		d.dbs[name] = db
+	}
+	return &fakeConn{db: db}, nil
+}
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{db: c.db, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) { return nil, errors.New("transactions not supported") }
+
+type fakeStmt struct {
+	db    *fakeDB
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	if s.query == "CREATE TABLE IF NOT EXISTS notifier_state (key TEXT PRIMARY KEY, value BLOB NOT NULL)" {
+		if s.db.rows == nil {
+			s.db.rows = make(map[string][]byte)
+		}
+		return driver.RowsAffected(0), nil
+	}
+	if s.db.rows == nil {
+		return nil, errors.New("no such table: notifier_state")
+	}
+	switch s.query {
+	case "INSERT INTO notifier_state (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value":
+		value, ok := args[1].([]byte)
+		if !ok {
+			return nil, fmt.Errorf("NOT NULL constraint failed: notifier_state.value (got %T)", args[1])
+		}
+		s.db.rows[args[0].(string)] = append([]byte{}, value...)
+		return driver.RowsAffected(1), nil
+	case "DELETE FROM notifier_state WHERE key = ?":
+		key := args[0].(string)
+		_, ok := s.db.rows[key]
+		delete(s.db.rows, key)
+		if ok {
+			return driver.RowsAffected(1), nil
+		}
+		return driver.RowsAffected(0), nil
+	}
+	return nil, fmt.Errorf("unexpected statement %q", s.query)
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	if s.query != "SELECT value FROM notifier_state WHERE key = ?" {
+		return nil, fmt.Errorf("unexpected query %q", s.query)
+	}
+	if s.db.rows == nil {
+		return nil, errors.New("no such table: notifier_state")
+	}
+	rows := &fakeRows{}
+	if value, ok := s.db.rows[args[0].(string)]; ok {
+		rows.values = [][]byte{append([]byte{}, value...)}
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	values [][]byte
+}
+
+func (r *fakeRows) Columns() []string { return []string{"value"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
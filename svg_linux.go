@@ -0,0 +1,65 @@
+//go:build linux
+
+package notifications
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// rasterizeIcon converts an SVG icon path to a PNG of the configured size,
+// for daemons that can't render SVG icons passed as paths. Icon names,
+// other formats and failed conversions are returned unchanged.
+func (n *Notifier) rasterizeIcon(icon string) string {
+	size := n.opts().RasterizeSVGIcons
+	if size <= 0 {
+		return icon
+	}
+
+	path := strings.TrimPrefix(icon, "file://")
+	if !filepath.IsAbs(path) || !strings.EqualFold(filepath.Ext(path), ".svg") {
+		return icon
+	}
+
+	if png, ok := n.rasterized[path]; ok {
+		return png
+	}
+
+	png, err := rasterizeSVG(path, size)
+	if err != nil {
+		png = icon // Let the daemon try the SVG itself
+	}
+
+	if n.rasterized == nil {
+		n.rasterized = make(map[string]string)
+	}
+	n.rasterized[path] = png
+	return png
+}
+
+// rasterizeSVG renders an SVG file to a cached PNG using rsvg-convert
+func rasterizeSVG(path string, size int) (string, error) {
+	converter, err := exec.LookPath("rsvg-convert")
+	if err != nil {
+		return "", fmt.Errorf("rsvg-convert not found: %w", err)
+	}
+
+	dir, err := cacheDir("icons")
+	if err != nil {
+		return "", err
+	}
+	out := filepath.Join(dir, fmt.Sprintf("%s-%d.png", cacheKey(path), size))
+	if fileExists(out) {
+		return out, nil
+	}
+
+	sizeArg := strconv.Itoa(size)
+	cmd := exec.Command(converter, "-w", sizeArg, "-h", sizeArg, "-f", "png", "-o", out, path)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to rasterize %s: %w: %s", path, err, output)
+	}
+	return out, nil
+}
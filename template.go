@@ -0,0 +1,175 @@
+package notifications
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// TemplateData is what summary and body templates are executed with. The
+// track's fields are available directly, e.g. {{.Title}}.
+type TemplateData struct {
+	*TrackInfo
+	State   PlaybackState // Current playback state
+	Chapter *Chapter      // Chapter playing (nil for none)
+}
+
+// templateFuncs are the helpers available to templates
+var templateFuncs = template.FuncMap{
+	"truncate": truncate,
+	"duration": formatDuration,
+	"ltr":      func(s string) string { return "\u2066" + s + "\u2069" },
+	"rtl":      func(s string) string { return "\u2067" + s + "\u2069" },
+	"isolate":  func(s string) string { return "\u2068" + s + "\u2069" },
+}
+
+// TemplateSet is a summary and body template for one locale. An empty
+// template falls back to Options.SummaryTemplate or BodyTemplate.
+type TemplateSet struct {
+	Summary string
+	Body    string
+}
+
+// templateRenderer renders notifications from text/template sources
+type templateRenderer struct {
+	summary *template.Template // nil keeps the default summary
+	body    *template.Template // nil keeps the default body
+	base    Renderer           // Renders the defaults (nil for DefaultRenderer)
+}
+
+// NewTemplateRenderer creates a renderer from text/template sources for the
+// summary and body. Either may be empty to keep the default. Templates are
+// executed with a TemplateData and can use these functions:
+//
+//	truncate n s  shortens s to n characters, ending with "…"
+//	duration d    formats a duration as "3:45" or "1:02:03"
+//	ltr s         shows s left-to-right whatever the surrounding text
+//	rtl s         shows s right-to-left whatever the surrounding text
+//	isolate s     shows s in the direction of its first strong character
+//
+// The direction helpers keep e.g. an Arabic artist from reordering a
+// Latin title around it.
+func NewTemplateRenderer(summary, body string) (Renderer, error) {
+	var r templateRenderer
+	var err error
+	if r.summary, err = parseTemplate("summary", summary); err != nil {
+		return nil, err
+	}
+	if r.body, err = parseTemplate("body", body); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// templates returns the summary and body templates for the locale
+func (o Options) templates() (summary, body string) {
+	summary, body = o.SummaryTemplate, o.BodyTemplate
+	if len(o.Templates) == 0 {
+		return summary, body
+	}
+
+	locale := o.Locale
+	if locale == "" {
+		locale = environmentLocale()
+	}
+	set, ok := o.Templates[locale]
+	if !ok {
+		// Fall back from a territory ("pt_BR") to the language ("pt")
+		language, _, _ := strings.Cut(locale, "_")
+		set, ok = o.Templates[language]
+	}
+	if !ok {
+		return summary, body
+	}
+
+	if set.Summary != "" {
+		summary = set.Summary
+	}
+	if set.Body != "" {
+		body = set.Body
+	}
+	return summary, body
+}
+
+// environmentLocale returns the session's message locale without its
+// encoding or modifier, e.g. "de_DE" for "de_DE.UTF-8@euro"
+func environmentLocale() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			value, _, _ = strings.Cut(value, ".")
+			value, _, _ = strings.Cut(value, "@")
+			return value
+		}
+	}
+	return ""
+}
+
+// parseTemplate parses a template source, returning nil for an empty one
+func parseTemplate(name, text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New(name).Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+// Render implements Renderer
+func (r *templateRenderer) Render(track *TrackInfo, state PlaybackState) (Payload, error) {
+	var base Renderer = DefaultRenderer{}
+	if r.base != nil {
+		base = r.base
+	}
+	payload, err := base.Render(track, state)
+	if err != nil {
+		return payload, err
+	}
+	if payload.ImageURL == "" {
+		payload.ImageURL = track.ImageURL
+	}
+
+	data := TemplateData{TrackInfo: track, State: state, Chapter: track.currentChapter()}
+	if r.summary != nil {
+		if payload.Summary, err = execute(r.summary, data); err != nil {
+			return payload, err
+		}
+	}
+	if r.body != nil {
+		if payload.Body, err = execute(r.body, data); err != nil {
+			return payload, err
+		}
+	}
+	return payload, nil
+}
+
+// execute runs a template, trimming the surrounding whitespace that
+// multi-line template sources tend to leave
+func execute(tmpl *template.Template, data TemplateData) (string, error) {
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// truncate shortens s to at most n characters
+func truncate(n int, s string) string {
+	runes := []rune(s)
+	if n <= 0 || len(runes) <= n {
+		return s
+	}
+	return string(runes[:n-1]) + "…"
+}
+
+// formatDuration formats a track length, e.g. "3:45" or "1:02:03"
+func formatDuration(d time.Duration) string {
+	seconds := int(d.Round(time.Second) / time.Second)
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds/60%60, seconds%60)
+	}
+	return fmt.Sprintf("%d:%02d", seconds/60, seconds%60)
+}
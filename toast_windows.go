@@ -0,0 +1,328 @@
+//go:build windows
+
+package notifications
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultBackend is used when Options.Backend is empty
+const defaultBackend = "toast"
+
+// defaultFallbacks are tried when the default backend can't be opened
+var defaultFallbacks []string
+
+// toastAppID is the AppUserModelID toasts are shown under. Toasts need a
+// registered app; PowerShell's is always present.
+const toastAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+// toastGroup groups this package's toasts in the notification history
+const toastGroup = "music"
+
+const (
+	// maxToastActions is the number of buttons a toast can show
+	maxToastActions = 5
+
+	// toastWait is how long a toast with buttons is watched for clicks,
+	// including from the action center
+	toastWait = time.Hour
+)
+
+func init() {
+	Register("toast", newToastBackend)
+}
+
+// toastScript shows the toast in $env:TOAST_XML, tagged so a later toast
+// with the same tag replaces it
+const toastScript = `
+$ErrorActionPreference = 'Stop'
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
+$xml = New-Object Windows.Data.Xml.Dom.XmlDocument
+$xml.LoadXml($env:TOAST_XML)
+$toast = New-Object Windows.UI.Notifications.ToastNotification $xml
+$toast.Tag = $env:TOAST_TAG
+$toast.Group = $env:TOAST_GROUP
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($env:TOAST_APP).Show($toast)
+`
+
+// toastActionScript shows the toast like toastScript, then writes
+// "shown" and waits for the toast's first event: "activated <arguments>"
+// for a click or "dismissed <reason>" for the toast going away
+const toastActionScript = `
+$ErrorActionPreference = 'Stop'
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.Data.Xml.Dom.XmlDocument, Windows.Data.Xml.Dom.XmlDocument, ContentType = WindowsRuntime] | Out-Null
+$xml = New-Object Windows.Data.Xml.Dom.XmlDocument
+$xml.LoadXml($env:TOAST_XML)
+$toast = New-Object Windows.UI.Notifications.ToastNotification $xml
+$toast.Tag = $env:TOAST_TAG
+$toast.Group = $env:TOAST_GROUP
+Register-ObjectEvent -InputObject $toast -EventName Activated -SourceIdentifier toast.activated | Out-Null
+Register-ObjectEvent -InputObject $toast -EventName Dismissed -SourceIdentifier toast.dismissed | Out-Null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($env:TOAST_APP).Show($toast)
+[Console]::Out.WriteLine('shown')
+$e = Wait-Event -Timeout $env:TOAST_WAIT
+if ($e -eq $null) { exit }
+switch ($e.SourceIdentifier) {
+  'toast.activated' { [Console]::Out.WriteLine('activated ' + $e.SourceArgs[1].Arguments) }
+  'toast.dismissed' { [Console]::Out.WriteLine('dismissed ' + [int]$e.SourceArgs[1].Reason) }
+}
+`
+
+// toastCloseReasons map ToastDismissalReason values to close reasons
+var toastCloseReasons = map[string]CloseReason{
+	"0": CloseDismissed, // UserCanceled
+	"1": CloseClosed,    // ApplicationHidden
+	"2": CloseExpired,   // TimedOut
+}
+
+// dismissScript removes the toast tagged $env:TOAST_TAG
+const dismissScript = `
+$ErrorActionPreference = 'Stop'
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+[Windows.UI.Notifications.ToastNotificationManager]::History.Remove($env:TOAST_TAG, $env:TOAST_GROUP, $env:TOAST_APP)
+`
+
+// toastBackend shows WinRT toast notifications through PowerShell, so
+// no cgo or COM bindings are needed. A toast with buttons keeps its
+// PowerShell process running to report the click.
+type toastBackend struct {
+	powershell string
+	config     BackendConfig
+
+	mu       sync.Mutex
+	nextID   uint32
+	watchers map[uint32]*exec.Cmd // Processes waiting for clicks, by toast
+}
+
+// newToastBackend checks that PowerShell is available
+func newToastBackend(config BackendConfig) (Backend, error) {
+	powershell, err := exec.LookPath("powershell.exe")
+	if err != nil {
+		return nil, fmt.Errorf("toast notifications need PowerShell: %w", err)
+	}
+	return &toastBackend{
+		powershell: powershell,
+		config:     config,
+		nextID:     firstID(),
+		watchers:   make(map[uint32]*exec.Cmd),
+	}, nil
+}
+
+// Send shows a toast with the summary as its title and up to two body lines
+func (b *toastBackend) Send(note *Notification) (uint32, error) {
+	id := note.ReplacesID
+	if id == 0 {
+		b.mu.Lock()
+		b.nextID++
+		id = b.nextID
+		b.mu.Unlock()
+	}
+
+	// The replaced toast's clicks and dismissal are no longer news
+	b.unwatch(id)
+	if len(note.Actions) > 0 {
+		if err := b.watch(id, toastXML(note)); err != nil {
+			return 0, err
+		}
+		return id, nil
+	}
+	if err := b.run(toastScript, id, toastXML(note)); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Dismiss removes a toast from the action center
+func (b *toastBackend) Dismiss(id uint32) error {
+	b.unwatch(id)
+	return b.run(dismissScript, id, "")
+}
+
+// Capabilities returns what toasts support through this backend
+func (b *toastBackend) Capabilities() Capabilities {
+	return Capabilities{
+		Images:      true,
+		Actions:     true,
+		Replacement: true,
+	}
+}
+
+// Close stops waiting for clicks on the toasts shown
+func (b *toastBackend) Close() error {
+	b.mu.Lock()
+	watchers := b.watchers
+	b.watchers = make(map[uint32]*exec.Cmd)
+	b.mu.Unlock()
+	for _, cmd := range watchers {
+		cmd.Process.Kill()
+	}
+	return nil
+}
+
+// run executes a PowerShell script for a toast without flashing a console
+func (b *toastBackend) run(script string, id uint32, toast string) error {
+	if out, err := b.command(script, id, toast).CombinedOutput(); err != nil {
+		return fmt.Errorf("powershell: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// command prepares a PowerShell script for a toast
+func (b *toastBackend) command(script string, id uint32, toast string) *exec.Cmd {
+	cmd := exec.Command(b.powershell, "-NoProfile", "-NonInteractive", "-Command", script)
+	cmd.Env = append(os.Environ(),
+		"TOAST_XML="+toast,
+		"TOAST_TAG="+strconv.FormatUint(uint64(id), 10),
+		"TOAST_GROUP="+toastGroup,
+		"TOAST_APP="+toastAppID,
+		"TOAST_WAIT="+strconv.Itoa(int(toastWait/time.Second)),
+	)
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	return cmd
+}
+
+// watch shows a toast with buttons, returning once it is shown and
+// reporting its click or dismissal from a goroutine
+func (b *toastBackend) watch(id uint32, toast string) error {
+	cmd := b.command(toastActionScript, id, toast)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("powershell: %w", err)
+	}
+
+	lines := bufio.NewScanner(stdout)
+	if !lines.Scan() || lines.Text() != "shown" {
+		err := cmd.Wait()
+		return fmt.Errorf("powershell: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	b.mu.Lock()
+	b.watchers[id] = cmd
+	b.mu.Unlock()
+	go b.report(id, cmd, lines)
+	return nil
+}
+
+// report waits for a watched toast's event and passes it on, unless the
+// toast was replaced or dismissed meanwhile
+func (b *toastBackend) report(id uint32, cmd *exec.Cmd, lines *bufio.Scanner) {
+	var event string
+	if lines.Scan() {
+		event = lines.Text()
+	}
+	cmd.Wait()
+
+	b.mu.Lock()
+	current := b.watchers[id] == cmd
+	if current {
+		delete(b.watchers, id)
+	}
+	b.mu.Unlock()
+	if !current {
+		return
+	}
+
+	kind, arg, _ := strings.Cut(event, " ")
+	switch kind {
+	case "activated":
+		// Clicking a toast closes it, as daemons close clicked notifications
+		if arg != "" && b.config.OnAction != nil {
+			b.config.OnAction(id, arg)
+		}
+		if b.config.OnClosed != nil {
+			b.config.OnClosed(id, CloseDismissed)
+		}
+	case "dismissed":
+		reason, ok := toastCloseReasons[arg]
+		if !ok {
+			reason = CloseUndefined
+		}
+		if b.config.OnClosed != nil {
+			b.config.OnClosed(id, reason)
+		}
+	}
+}
+
+// unwatch stops waiting for clicks on toast id
+func (b *toastBackend) unwatch(id uint32) {
+	b.mu.Lock()
+	cmd := b.watchers[id]
+	delete(b.watchers, id)
+	b.mu.Unlock()
+	if cmd != nil {
+		cmd.Process.Kill()
+	}
+}
+
+// toastXML builds the ToastGeneric document for a notification. Toasts
+// show at most three text lines, so extra body lines are joined. Actions
+// become buttons whose arguments are the action IDs, and the "default"
+// action the toast's own launch arguments.
+func toastXML(note *Notification) string {
+	lines := []string{note.Summary}
+	if note.Body != "" {
+		body := strings.Split(note.Body, "\n")
+		if len(body) > 2 {
+			body = []string{body[0], strings.Join(body[1:], " · ")}
+		}
+		lines = append(lines, body...)
+	}
+
+	var sb strings.Builder
+	duration := "short"
+	if note.Timeout == 0 || note.Timeout > 10000 {
+		duration = "long"
+	}
+	sb.WriteString(`<toast duration="` + duration + `"`)
+	var buttons []Action
+	for _, action := range note.Actions {
+		if action.ID == "default" {
+			sb.WriteString(` launch="default"`)
+		} else if len(buttons) < maxToastActions {
+			buttons = append(buttons, action)
+		}
+	}
+	sb.WriteString(`><visual><binding template="ToastGeneric">`)
+	for _, line := range lines {
+		sb.WriteString("<text>")
+		xml.EscapeText(&sb, []byte(line))
+		sb.WriteString("</text>")
+	}
+	if note.ImagePath != "" {
+		sb.WriteString(`<image placement="appLogoOverride" src="`)
+		xml.EscapeText(&sb, []byte("file:///"+strings.ReplaceAll(note.ImagePath, `\`, "/")))
+		sb.WriteString(`"/>`)
+	}
+	sb.WriteString(`</binding></visual>`)
+	if len(buttons) > 0 {
+		sb.WriteString(`<actions>`)
+		for _, action := range buttons {
+			sb.WriteString(`<action content="`)
+			xml.EscapeText(&sb, []byte(action.Label))
+			sb.WriteString(`" arguments="`)
+			xml.EscapeText(&sb, []byte(action.ID))
+			sb.WriteString(`"/>`)
+		}
+		sb.WriteString(`</actions>`)
+	}
+	// Don't chime over the music
+	sb.WriteString(`<audio silent="true"/></toast>`)
+	return sb.String()
+}
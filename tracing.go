@@ -0,0 +1,35 @@
+package notifications
+
+import "context"
+
+// Tracer starts spans around the stages of showing a notification, for
+// apps instrumented with OpenTelemetry or a similar tracing system. Spans
+// are named "notifications.show", with "notifications.render",
+// "notifications.art" and "notifications.send" (the backend call, e.g.
+// D-Bus Notify) as its children.
+type Tracer interface {
+	// Start starts a span as a child of the span in ctx, returning a
+	// context carrying the new one
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer
+type Span interface {
+	// End finishes the span, marking it failed if err is non-nil
+	End(err error)
+}
+
+// noopSpan is used without a Tracer
+type noopSpan struct{}
+
+func (noopSpan) End(error) {}
+
+// trace starts a span with Options.Tracer, if set. A nil ctx starts none,
+// so live updates and other sends outside Notify don't each start a trace.
+func (n *Notifier) trace(ctx context.Context, name string) (context.Context, Span) {
+	tracer := n.opts().Tracer
+	if tracer == nil || ctx == nil {
+		return ctx, noopSpan{}
+	}
+	return tracer.Start(ctx, name)
+}
@@ -0,0 +1,95 @@
+package notifications
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Equal reports whether t and other are the same track: title, artist and
+// album match, ignoring leading, trailing and repeated whitespace. Art,
+// position, source and loudness may differ. Deduplication uses the same
+// comparison.
+func (t *TrackInfo) Equal(other *TrackInfo) bool {
+	if t == nil || other == nil {
+		return t == other
+	}
+	return trackKey(t) == trackKey(other)
+}
+
+// Diff returns the names of the fields that differ between t and other,
+// ignoring whitespace-only changes to text. Position is never reported, as
+// it changes all the time.
+func (t *TrackInfo) Diff(other *TrackInfo) []string {
+	var a, b TrackInfo
+	if t != nil {
+		a = *t
+	}
+	if other != nil {
+		b = *other
+	}
+
+	var fields []string
+	text := func(name, x, y string) {
+		if collapseSpace(x) != collapseSpace(y) {
+			fields = append(fields, name)
+		}
+	}
+	text("Title", a.Title, b.Title)
+	text("Artist", a.Artist, b.Artist)
+	text("Album", a.Album, b.Album)
+	if a.TrackNumber != b.TrackNumber {
+		fields = append(fields, "TrackNumber")
+	}
+	text("Station", a.Station, b.Station)
+	text("ImageURL", a.ImageURL, b.ImageURL)
+	if a.Duration != b.Duration {
+		fields = append(fields, "Duration")
+	}
+	text("Source", a.Source, b.Source)
+	if a.Origin != b.Origin {
+		fields = append(fields, "Origin")
+	}
+	if a.ReplayGain != b.ReplayGain {
+		fields = append(fields, "ReplayGain")
+	}
+	if a.Loudness != b.Loudness {
+		fields = append(fields, "Loudness")
+	}
+	return fields
+}
+
+// withSource returns the track with Source derived from Origin when only
+// the latter is set, so source-based settings work with either
+func (t *TrackInfo) withSource() *TrackInfo {
+	if t.Source != "" || t.Origin.Player == "" {
+		return t
+	}
+	track := *t
+	track.Source = t.Origin.key()
+	return &track
+}
+
+// trackKey identifies a track for deduplication
+func trackKey(track *TrackInfo) string {
+	return collapseSpace(track.Title) + "-" + collapseSpace(track.Artist) + "-" + collapseSpace(track.Album)
+}
+
+// collapseSpace trims s and collapses runs of whitespace to one space.
+// Strings that are already clean are returned without allocating.
+func collapseSpace(s string) string {
+	afterSpace := true // Leading whitespace needs cleaning
+	for _, r := range s {
+		if !unicode.IsSpace(r) {
+			afterSpace = false
+			continue
+		}
+		if afterSpace || r != ' ' {
+			return strings.Join(strings.Fields(s), " ")
+		}
+		afterSpace = true
+	}
+	if afterSpace && s != "" {
+		return strings.TrimRight(s, " ") // Trailing space
+	}
+	return s
+}
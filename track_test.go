@@ -0,0 +1,22 @@
+package notifications
+
+import "testing"
+
+func TestCollapseSpace(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"clean", "clean"},
+		{"two words", "two words"},
+		{" leading", "leading"},
+		{"trailing ", "trailing"},
+		{"in  between", "in between"},
+		{"tab\there", "tab here"},
+		{" \n ", ""},
+	} {
+		if got := collapseSpace(tt.in); got != tt.want {
+			t.Errorf("collapseSpace(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package notifications
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("unifiedpush", newUnifiedPushBackend)
+}
+
+const (
+	// pushTimeout bounds one POST to the push server
+	pushTimeout = 10 * time.Second
+
+	// pushTTL is how long the push server keeps a message for an offline
+	// phone; now-playing news older than this isn't worth delivering
+	pushTTL = time.Hour
+
+	// pushTopic makes the push server replace an undelivered track message
+	// with the next one, instead of queueing every track
+	pushTopic = "now-playing"
+)
+
+// pushUrgencies map urgencies to the Web Push Urgency header (RFC 8030)
+var pushUrgencies = map[Urgency]string{
+	UrgencyLow:      "low",
+	UrgencyNormal:   "normal",
+	UrgencyCritical: "high",
+}
+
+// PushSchemaVersion is the version of the JSON the "unifiedpush" backend
+// posts, sent in each message's "schema" field. It changes only when a
+// field is removed or changes meaning; added fields keep the version.
+const PushSchemaVersion = 1
+
+//go:embed pushschema.json
+var pushSchema []byte
+
+// PushSchema returns the JSON Schema of the messages the "unifiedpush"
+// backend posts, for companion apps to validate them against
+func PushSchema() []byte {
+	return slices.Clone(pushSchema)
+}
+
+// pushMessage is the JSON body posted for each notification, for the app
+// on the phone to show. Keep pushschema.json in sync with it.
+type pushMessage struct {
+	Schema  int        `json:"schema"` // PushSchemaVersion
+	App     string     `json:"app"`
+	Event   string     `json:"event"` // "track" or "message"
+	Title   string     `json:"title"`
+	Body    string     `json:"body,omitempty"`
+	Urgency string     `json:"urgency"` // "low", "normal" or "critical"
+	Time    time.Time  `json:"time"`    // When it happened, earlier than the push for messages queued while offline
+	Track   *pushTrack `json:"track,omitempty"`
+}
+
+// pushTrack is the track a pushMessage is about
+type pushTrack struct {
+	Title    string `json:"title,omitempty"`
+	Artist   string `json:"artist,omitempty"`
+	Album    string `json:"album,omitempty"`
+	Station  string `json:"station,omitempty"`
+	ImageURL string `json:"image_url,omitempty"` // Only remote art
+	Source   string `json:"source,omitempty"`
+	Host     string `json:"host,omitempty"`
+}
+
+// unifiedPushBackend posts notifications to a UnifiedPush endpoint, which
+// the user's push server forwards to their phone
+type unifiedPushBackend struct {
+	endpoint string
+	host     string // Push server, for rate limiting
+	token    string // Access token for the push server (empty for none)
+	client   *http.Client
+}
+
+// newUnifiedPushBackend posts to Options.PushEndpoint, or else the
+// "unifiedpush-endpoint" credential
+func newUnifiedPushBackend(config BackendConfig) (Backend, error) {
+	endpoint := config.Options.PushEndpoint
+	if endpoint == "" {
+		var err error
+		if endpoint, err = config.Options.credential("unifiedpush-endpoint"); err != nil {
+			return nil, err
+		}
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("unifiedpush backend needs Options.PushEndpoint or a unifiedpush-endpoint credential")
+	}
+	token, err := config.Options.credential("unifiedpush-token")
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		if config.Options.PushEndpoint == "" {
+			return nil, fmt.Errorf("invalid unifiedpush-endpoint credential") // Don't log the secret
+		}
+		return nil, fmt.Errorf("invalid push endpoint %q", endpoint)
+	}
+	return &unifiedPushBackend{
+		endpoint: endpoint,
+		host:     u.Host,
+		token:    token,
+		client:   &http.Client{Timeout: pushTimeout},
+	}, nil
+}
+
+// Send posts the notification as a pushMessage
+func (b *unifiedPushBackend) Send(note *Notification) (uint32, error) {
+	message := pushMessage{
+		Schema:  PushSchemaVersion,
+		App:     note.AppName,
+		Event:   "message",
+		Title:   note.Summary,
+		Body:    note.Body,
+		Urgency: urgencyNames[note.Urgency],
+		Time:    note.Time,
+	}
+	if message.Time.IsZero() {
+		message.Time = time.Now()
+	}
+	if t := note.Track; t != nil {
+		message.Event = "track"
+		message.Track = &pushTrack{
+			Title:   t.Title,
+			Artist:  t.Artist,
+			Album:   t.Album,
+			Station: t.Station,
+			Source:  t.Source,
+			Host:    t.Origin.Host,
+		}
+		if strings.HasPrefix(t.ImageURL, "https://") || strings.HasPrefix(t.ImageURL, "http://") {
+			message.Track.ImageURL = t.ImageURL
+		}
+	}
+	data, err := json.Marshal(message)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, b.endpoint, bytes.NewReader(data))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.token)
+	}
+	req.Header.Set("TTL", strconv.Itoa(int(pushTTL/time.Second)))
+	if urgency, ok := pushUrgencies[note.Urgency]; ok {
+		req.Header.Set("Urgency", urgency)
+	}
+	if note.Track != nil {
+		req.Header.Set("Topic", pushTopic)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		if os.IsTimeout(err) {
+			err = fmt.Errorf("%w: %w", ErrTimeout, err)
+		} else {
+			err = fmt.Errorf("%w: %w", ErrOffline, err)
+		}
+		return 0, fmt.Errorf("failed to push notification: %w", err)
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return 0, &RateLimitError{Destination: b.host, After: retryAfter(resp.Header.Get("Retry-After"))}
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return 0, fmt.Errorf("push endpoint is no longer registered (HTTP %d)", resp.StatusCode)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return 0, fmt.Errorf("push server rejected notification (HTTP %d)", resp.StatusCode)
+	}
+	return 0, nil
+}
+
+// Destination returns the push server's host, whose rate limit the
+// notifier paces notifications by
+func (b *unifiedPushBackend) Destination() string {
+	return b.host
+}
+
+// retryAfter parses a Retry-After header, in seconds or as an HTTP date
+// (0 if it is missing or malformed)
+func retryAfter(header string) time.Duration {
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil {
+		return max(time.Until(at), 0)
+	}
+	return 0
+}
+
+// Capabilities reports no features; pushed messages can't be replaced once
+// delivered and have no buttons
+func (b *unifiedPushBackend) Capabilities() Capabilities {
+	return Capabilities{}
+}
+
+// Close closes idle connections to the push server
+func (b *unifiedPushBackend) Close() error {
+	b.client.CloseIdleConnections()
+	return nil
+}
@@ -0,0 +1,115 @@
+package notifications_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/go-music-players/notifications"
+)
+
+// jsonSchema is the part of a JSON Schema the push messages are checked
+// against
+type jsonSchema struct {
+	Required   []string               `json:"required"`
+	Properties map[string]*jsonSchema `json:"properties"`
+	Const      any                    `json:"const"`
+	Enum       []any                  `json:"enum"`
+}
+
+// check reports the ways value doesn't match the schema
+func (s *jsonSchema) check(path string, value any) []string {
+	var problems []string
+	if s.Const != nil && value != s.Const {
+		problems = append(problems, path+" is not the constant")
+	}
+	if s.Enum != nil && !slices.Contains(s.Enum, value) {
+		problems = append(problems, path+" is not one of the values listed")
+	}
+	object, ok := value.(map[string]any)
+	if !ok || s.Properties == nil {
+		return problems
+	}
+	for _, key := range s.Required {
+		if _, ok := object[key]; !ok {
+			problems = append(problems, path+"."+key+" is missing")
+		}
+	}
+	for key, v := range object {
+		property, ok := s.Properties[key]
+		if !ok {
+			problems = append(problems, path+"."+key+" is not in the schema")
+			continue
+		}
+		problems = append(problems, property.check(path+"."+key, v)...)
+	}
+	return problems
+}
+
+func TestPushSchema(t *testing.T) {
+	var schema jsonSchema
+	if err := json.Unmarshal(notifications.PushSchema(), &schema); err != nil {
+		t.Fatal(err)
+	}
+	if version := schema.Properties["schema"].Const; version != float64(notifications.PushSchemaVersion) {
+		t.Errorf("schema describes version %v, want %d", version, notifications.PushSchemaVersion)
+	}
+
+	var (
+		mu       sync.Mutex
+		messages []map[string]any
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var message map[string]any
+		if err := json.Unmarshal(data, &message); err != nil {
+			t.Errorf("posted %q: %v", data, err)
+		}
+		mu.Lock()
+		messages = append(messages, message)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	notifier, err := notifications.NewNotifier(notifications.WithBackend("unifiedpush"), notifications.WithOptions(func(o *notifications.Options) {
+		o.PushEndpoint = server.URL
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer notifier.Close()
+	track := &notifications.TrackInfo{
+		Title:    "Song",
+		Artist:   "Band",
+		Album:    "Album",
+		Station:  "Radio One",
+		ImageURL: "https://example.com/cover.jpg",
+		Source:   "mpd",
+		Origin:   notifications.Origin{Player: "mpd", Host: "living-room"},
+	}
+	if err := notifier.Notify(track, notifications.StatePlaying); err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.NotifyError("Playback failed", errors.New("no such file")); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(messages) != 2 {
+		t.Fatalf("posted %d messages, want 2", len(messages))
+	}
+	for _, message := range messages {
+		if _, ok := message["track"]; ok == (message["event"] == "message") {
+			t.Errorf("%s message has track: %v", message["event"], ok)
+		}
+		for _, problem := range schema.check("message", message) {
+			t.Errorf("%s message: %s", message["event"], problem)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux && !windows && !darwin
+
+package notifications
+
+import "fmt"
+
+// defaultBackend is used when Options.Backend is empty
+const defaultBackend = "unsupported"
+
+// defaultFallbacks are tried when the default backend can't be opened
+var defaultFallbacks []string
+
+func init() {
+	Register("unsupported", func(BackendConfig) (Backend, error) {
+		return nil, fmt.Errorf("desktop notifications are not supported on this platform")
+	})
+}